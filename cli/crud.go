@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/azr/generators/handlergen"
+	"github.com/azr/generators/loader"
+	"github.com/azr/generators/utils"
+)
+
+// scaffoldCRUD writes the CRUD funcs of the typeName type of the package of
+// pkgArgs next to it, like job_crud.go for Job, along with the store they
+// use, job_store.go, and its tests, job_store_test.go, returning the
+// package args holding them.
+func scaffoldCRUD(f *Flags, pkgArgs []string, typeName string) ([]string, error) {
+	if typeName == "" {
+		return nil, errors.New("crud needs the -type of the resources")
+	}
+	g := handlergen.Generator{}
+	if err := g.Parse(pkgArgs...); err != nil {
+		return nil, err
+	}
+	prefix := filepath.Join(loader.Dir(pkgArgs...), strings.ToLower(typeName))
+	files := []struct {
+		name   string
+		render func(w io.Writer, name string) error
+	}{
+		{prefix + "_crud.go", g.RenderCRUD},
+		{prefix + "_store.go", g.RenderCRUDStore},
+		{prefix + "_store_test.go", g.RenderCRUDTest},
+	}
+	srcs := make([][]byte, len(files))
+	for i, file := range files {
+		if utils.IsFile(file.name) {
+			return nil, errors.New(file.name + " already exists")
+		}
+		var src bytes.Buffer
+		if err := file.render(&src, typeName); err != nil {
+			return nil, err
+		}
+		srcs[i] = src.Bytes()
+	}
+	for i, file := range files {
+		if err := f.write(file.name, srcs[i]); err != nil {
+			return nil, err
+		}
+	}
+	if len(pkgArgs) > 0 && !utils.IsDirectory(pkgArgs[0]) {
+		// A list of files, the tests off it.
+		pkgArgs = append(pkgArgs, files[0].name, files[1].name)
+	}
+	return pkgArgs, nil
+}
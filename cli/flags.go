@@ -0,0 +1,257 @@
+// Package cli runs the generators from command line arguments. It is shared
+// by the handler and varhandler commands and by the generators command
+// bundling them, so they all take the same flags.
+package cli // import "github.com/azr/generators/cli"
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"runtime/debug"
+	"strings"
+)
+
+// ErrUsage is returned when required flags are missing; usage was printed.
+var ErrUsage = errors.New("missing required flags")
+
+// Flags are the flags of a generator, starting with the ones every
+// generator takes.
+type Flags struct {
+	*flag.FlagSet
+	Funcs  string // -func
+	Output string // -output
+	Check  bool   // -check
+	Diff   bool   // -diff
+	Write  bool   // -w
+	DryRun bool   // -n or -dry-run
+	Merge  bool   // -merge
+
+	version bool     // -version
+	stale   []string // With -check or -diff, output files that are not up to date.
+}
+
+// NewFlags returns the flags of the name command, with -func and -output,
+// described by output. Usage prints usage lines before the flag defaults.
+func NewFlags(name, output string, usage ...string) *Flags {
+	errorHandling := flag.ExitOnError
+	if serving {
+		errorHandling = flag.ContinueOnError
+	}
+	f := &Flags{FlagSet: flag.NewFlagSet(name, errorHandling)}
+	f.StringVar(&f.Funcs, "func", "", "comma-separated list of func names; must be set")
+	f.StringVar(&f.Output, "output", "", output)
+	f.BoolVar(&f.Check, "check", false, "only check the output files are up to date, failing with the stale ones otherwise")
+	f.BoolVar(&f.Diff, "diff", false, "print the diff of the output files that are not up to date instead of writing them, unless -w is set")
+	f.BoolVar(&f.Write, "w", false, "with -diff, also write the output files")
+	f.BoolVar(&f.DryRun, "dry-run", false, "only print the funcs that would be generated into each output file, without writing anything")
+	f.BoolVar(&f.DryRun, "n", false, "shorthand for -dry-run")
+	f.BoolVar(&f.version, "version", false, "print the version of the command and exit")
+	f.BoolVar(&f.Merge, "merge", false, "merge the generated code into the existing output files, keeping the declarations other runs generated, so several go:generate directives can share a file")
+	f.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s:\n", name)
+		for _, line := range usage {
+			fmt.Fprintf(os.Stderr, "\t%s\n", line)
+		}
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		f.PrintDefaults()
+	}
+	return f
+}
+
+// Parse parses args like flag.FlagSet.Parse, exiting after printing the
+// version with -version. When serving, it returns ErrUsage, or errVersion
+// after printing the version, instead of exiting.
+func (f *Flags) Parse(args []string) error {
+	if err := f.FlagSet.Parse(args); err != nil {
+		return ErrUsage // Only when serving: it exits on error otherwise.
+	}
+	if f.version {
+		version := Version()
+		if version == "" {
+			version = "(devel)"
+		}
+		fmt.Printf("%s %s\n", f.Name(), version)
+		if serving {
+			return errVersion
+		}
+		os.Exit(0)
+	}
+	return nil
+}
+
+// Version returns the version of the generators module the command was
+// built from, like v1.2.0, or "" if unknown, like when built from a checkout.
+func Version() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	version := info.Main.Version
+	if info.Main.Path != modulePath {
+		// The command is built into another module.
+		version = ""
+		for _, dep := range info.Deps {
+			if dep.Path == modulePath {
+				version = dep.Version
+			}
+		}
+	}
+	if version == "(devel)" {
+		return ""
+	}
+	return version
+}
+
+// modulePath is the path of the generators module.
+const modulePath = "github.com/azr/generators"
+
+// FuncNames returns the names set with -func.
+func (f *Flags) FuncNames() []string {
+	return split(f.Funcs)
+}
+
+// writes reports whether the output files are written, rather than only
+// checked, diffed or listed.
+func (f *Flags) writes() bool {
+	return !f.Check && !f.DryRun && (!f.Diff || f.Write)
+}
+
+// write writes src to the file named name, or to stdout if name is -. With
+// -check or -diff, it records whether the file holds src, printing the diff
+// with -diff. With -dry-run, it only prints the funcs src declares. With
+// -merge, src is merged into the file first, if it exists.
+func (f *Flags) write(name string, src []byte) error {
+	if f.Merge && name != "-" {
+		if old, err := ioutil.ReadFile(name); err == nil {
+			if src, err = merge(name, old, src); err != nil {
+				return err
+			}
+		}
+	}
+	if f.DryRun {
+		funcs, err := declared(src)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s: %s\n", name, strings.Join(funcs, ", "))
+		return nil
+	}
+	if name == "-" {
+		_, err := os.Stdout.Write(src)
+		return err
+	}
+	if f.Check || f.Diff {
+		old, err := ioutil.ReadFile(name)
+		if err != nil || !bytes.Equal(old, src) {
+			f.stale = append(f.stale, name)
+			if f.Diff {
+				d, err := diff(name, old, src)
+				if err != nil {
+					return err
+				}
+				os.Stdout.Write(d)
+			}
+		}
+	}
+	if !f.writes() {
+		return nil
+	}
+	if old, err := ioutil.ReadFile(name); err == nil && bytes.Equal(old, src) {
+		return nil // Keep the mtime of the file, for build caches and watchers.
+	}
+	if err := ioutil.WriteFile(name, src, 0644); err != nil {
+		return fmt.Errorf("writing output: %s", err)
+	}
+	return nil
+}
+
+// Stale returns an error listing the output files found stale with -check,
+// if any.
+func (f *Flags) Stale() error {
+	if !f.Check || len(f.stale) == 0 {
+		return nil
+	}
+	return fmt.Errorf("generated files are not up to date, regenerate them: %s", strings.Join(f.stale, ", "))
+}
+
+// declared returns the funcs and methods declared by src, like
+// PutJobHandlerJSON or (*Server).PutJobHandlerJSON.
+func declared(src []byte) ([]string, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), "", src, 0)
+	if err != nil {
+		return nil, err
+	}
+	var funcs []string
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+		name := fn.Name.Name
+		if fn.Recv != nil && len(fn.Recv.List) == 1 {
+			name = fmt.Sprintf("(%s).%s", types.ExprString(fn.Recv.List[0].Type), name)
+		}
+		funcs = append(funcs, name)
+	}
+	return funcs, nil
+}
+
+// diff returns the unified diff of the file named name from old to src, as
+// printed by diff -u.
+func diff(name string, old, src []byte) ([]byte, error) {
+	var files []string
+	for _, data := range [][]byte{old, src} {
+		f, err := ioutil.TempFile("", "generators")
+		if err != nil {
+			return nil, err
+		}
+		f.Close()
+		defer os.Remove(f.Name())
+		if err := ioutil.WriteFile(f.Name(), data, 0644); err != nil {
+			return nil, err
+		}
+		files = append(files, f.Name())
+	}
+	data, err := exec.Command("diff", "-u", "-L", name+".orig", "-L", name, files[0], files[1]).CombinedOutput()
+	if len(data) > 0 {
+		// diff exits with a non-zero status when the files differ.
+		return data, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("computing diff: %s", err)
+	}
+	return data, nil
+}
+
+// uncredited are the flags left out of the "Code generated by" header,
+// since they do not change the code generated.
+var uncredited = map[string]bool{"check": true, "diff": true, "w": true, "dry-run": true, "n": true, "merge": true, "v": true, "strict": true, "watch": true, "list": true, "allow-missing": true}
+
+// credit returns how the name command was run with args, without the
+// uncredited flags.
+func credit(name string, args []string) string {
+	by := []string{name}
+	for _, arg := range args {
+		flag := strings.SplitN(strings.TrimLeft(arg, "-"), "=", 2)[0]
+		if !strings.HasPrefix(arg, "-") || !uncredited[flag] {
+			by = append(by, arg)
+		}
+	}
+	return strings.Join(by, " ")
+}
+
+// split splits a comma-separated list; it is nil for an empty string.
+func split(list string) []string {
+	if list == "" {
+		return nil
+	}
+	return strings.Split(list, ",")
+}
@@ -0,0 +1,662 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/azr/generators/handlergen"
+	"github.com/azr/generators/loader"
+	"github.com/azr/generators/utils"
+)
+
+// Handler runs handler with args, name being how it was invoked, like
+// "handler" or "generators handler". args may start with a consumer,
+// command, job, health or crud subcommand.
+func Handler(name string, args []string) error {
+	if socket := os.Getenv(ServerEnv); socket != "" && !serving {
+		if served, err := forward(socket, name, args); served {
+			return err
+		}
+	}
+	command := ""
+	if len(args) > 0 {
+		switch args[0] {
+		case "consumer", "command", "job", "health", "crud":
+			command = args[0]
+		case "serve":
+			if len(args) != 2 || serving {
+				fmt.Fprintf(os.Stderr, "Usage: %s serve socket\n", name)
+				return ErrUsage
+			}
+			return serve(args[1])
+		}
+	}
+
+	f := NewFlags(name, "output file name, or - for stdout; default srcdir/generated_handlers.go",
+		name+" [flags] -func F -encoding 'encoding/json' [directory]",
+		name+" [flags] -func F -encoding 'encoding/json' files... # Must be a single package",
+		name+" [flags] -func F -encoding 'encoding/json' ./... # For every package of the tree declaring F",
+		name+" [flags] -all -encoding 'encoding/json' [directory] # For every func of a supported signature",
+		name+" consumer [flags] -queue nats -func F -encoding 'encoding/json' [directory] # To consume messages instead",
+		name+" command [flags] -func F -encoding 'encoding/json' [directory] # To generate cobra commands instead",
+		name+" job [flags] -func F -encoding 'gopkg.in/yaml.v3' [directory] # To generate jobs loading their config",
+		name+" health [flags] -encoding 'encoding/json' [directory] # To generate /healthz and /readyz handlers running the registered Checkers",
+		name+" crud [flags] -type T -encoding 'encoding/json' [directory] # To scaffold the funcs creating, getting, listing, updating and deleting Ts, with their handlers",
+		name+" [flags] [directory] # To read srcdir/"+handlergen.ConfigFile+", or generate for the funcs annotated "+handlergen.Annotation,
+		name+" serve socket # To make the runs setting "+ServerEnv+"=socket, keeping the packages loaded between them",
+		"For more information, see: http://godoc.org/github.com/azr/generators/handler",
+	)
+	var (
+		encodingPkgNames = f.String("encoding", "", "comma-separated list of encoding pkgs; must be set")
+		websocket        = f.Bool("websocket", false, "also generate a WebSocket endpoint for each func and encoding")
+		stream           = f.String("stream", "", "streaming format for funcs returning a slice or a chan: ndjson; default is Server-Sent Events for chans only")
+		source           = f.String("source", "", "where parameters of a basic type, like F(id int), are read from: body, query, path or header, reading the value named after the parameter; default body, or query along other parameters")
+		contentType      = f.String("content-type", "application/octet-stream", "Content-Type of responses copied from an io.Reader or a []byte")
+		disposition      = f.String("content-disposition", "", "Content-Disposition of responses copied from an io.Reader or a []byte, like 'attachment; filename=\"report.csv\"'; default none")
+		charset          = f.String("charset", "", "what to do with request bodies of another charset than UTF-8: reject, responding 415, or transcode, with golang.org/x/text; default decoding them as is")
+		xmlHeader        = f.Bool("xml-header", false, "write the xml.Header preamble before the encoding/xml responses")
+		xmlRoot          = f.String("xml-root", "", "element the encoding/xml slice responses are wrapped in, like jobs; default none, listing the elements")
+		negotiate        = f.Bool("negotiate", false, "also generate for each func a handler calling the one of the encoding the Accept header prefers, responding 406 if none is accepted")
+		defaultEncoding  = f.String("default-encoding", "", "with -negotiate: encoding pkg answering */* and requests without Accept header; default the first one of the func")
+		problem          = f.Bool("problem", false, "write RFC 7807 application/problem+json error responses, instead of empty bodies")
+		errorEncoder     = f.String("error-encoder", "", "func of the package the http handlers call with (w, r, status, err) when they fail, like WriteAPIError; default writing the status only")
+		errorHandler     = f.String("error-handler", "", "func of the package the http handlers call with (w, r, 400, err) when they cannot decode the request, like varhandler's HandleHttpErrorWithDefaultStatus")
+		envelope         = f.Bool("envelope", false, "wrap the responses of the http handlers in an Envelope struct, declared with them, holding their data and meta, or their error")
+		jsonapi          = f.Bool("jsonapi", false, "render the responses of the http handlers as JSON:API documents, application/vnd.api+json, declaring their types")
+		hal              = f.Bool("hal", false, "render the responses of the http handlers as HAL resources, application/hal+json, linked to by the GET routes of the funcs")
+		pagination       = f.Bool("pagination", false, "declare a Page struct the http handlers read from the limit and offset, or page and per_page, query params, passing it to the funcs taking one, and set X-Total-Count and Link headers from the Total() int of the responses")
+		pageSize         = f.Int("page-size", 20, "with -pagination: limit of the pages when the request does not tell it")
+		maxPageSize      = f.Int("max-page-size", 100, "with -pagination: largest limit a request can tell, responding 400 above")
+		queue            = f.String("queue", "nats", "consumer only: message queue to consume from: nats or kafka")
+		envPrefix        = f.String("env-prefix", "", "job only: prefix of the environment variables fields are loaded from")
+		typeName         = f.String("type", "", "crud only: type of the resources to scaffold the funcs of, like Job, writing them to srcdir/job_crud.go and their store to srcdir/job_store.go")
+		tpl              = f.String("template", "", "path to a go template replacing the default handler template")
+		tplDir           = f.String("template-dir", "", "directory of go templates replacing the default ones with the same name, like handler.gotpl")
+		hooks            = f.String("hook", "", "comma-separated list of hook executables; see the Hooks doc")
+		all              = f.Bool("all", false, "generate for every exported func of a supported signature instead of the -func ones")
+		exclude          = f.String("exclude", "", "comma-separated list of func names or patterns not to generate for, with -all or -func patterns")
+		list             = f.Bool("list", false, "only print the funcs found, their parameter type and encodings, without writing anything")
+		splitOutput      = f.Bool("split", false, "write the code of each encoding to its own file, like generated_handlers_json.go, built unless the nojson build tag is set")
+		header           = f.String("header", "", "path to a file, like a license, printed at the top of the generated files before the \"Code generated\" line")
+		tagsLine         = f.String("tags-line", "", "build constraint written as a //go:build line at the top of the generated files, like '!nohandlers'; default none")
+		allowMissing     = f.Bool("allow-missing", false, "only warn about the -func funcs that are not found instead of failing")
+		strict           = f.Bool("strict", false, "fail on every warning, like a func not found with -allow-missing or a field a command has no flag for")
+		verbose          = f.Bool("v", false, "log the files parsed, the funcs matched, the types resolved and the templates executed")
+		test             = f.Bool("test", false, "also parse the _test.go files of the package, writing to generated_handlers_test.go")
+		xtest            = f.Bool("xtest", false, "parse the _test.go files of the external test package, like foo_test, alone, writing to generated_handlers_test.go")
+		watchFiles       = f.Bool("watch", false, "keep running, generating again each time a Go file, template or config file of the package changes")
+		perFile          = f.Bool("per-file", false, "write the code of the funcs of each source file next to it, like jober_handlers.go for jober.go, instead of to one output file")
+		postman          = f.String("postman", "", "also write the Postman collection of the routes, with example bodies, to this file, like handlers.postman_collection.json")
+		openAPI          = f.String("openapi", "", "also write the OpenAPI spec of the routes to this file, like openapi.json, RegisterHandlers serving it at /openapi.json with a Swagger UI at /docs")
+		pkg              = f.String("pkg", "", "directory of a package to generate into, like ./httphandlers, importing the package of the funcs; default is the package of the funcs")
+		handlerName      = f.String("name", "", "go template of the handler func names, like 'Handle{{.Func}}' or '{{.Func}}{{.Encoding}}Endpoint'; default '{{.Func}}Handler{{.Encoding}}'")
+		unexported       = f.Bool("unexported", false, "lowercase the first letter of the generated funcs, like putJobHandlerJSON")
+		receiver         = f.String("receiver", "", "type the funcs are methods of, like Server: generated funcs then are methods of *Server calling s.F")
+		iface            = f.String("interface", "", "interface the funcs are methods of, like JobAPI, generating NewJobAPIHandlers(impl JobAPI) returning the handlers of impl; default every method of a supported signature")
+		values           = f.Bool("values", false, "also declare each http handler as an http.Handler, like PutJobJSON; a method returning it with -receiver")
+		requestID        = f.Bool("request-id", false, "declare WithRequestID, a middleware storing the X-Request-Id of the requests, or a random one, in their context and setting it on the responses, wrapping the handlers RegisterHandlers registers")
+		manifest         = f.Bool("manifest", false, "also declare Routes, returning the RouteInfo of each route registered: its method, path, handler and the types of its body and responses")
+		urls             = f.Bool("urls", false, "also declare a func building the path of each route from the parameters read from its wildcards, like PutJobURL(id) for PUT /jobs/{id}")
+		consts           = f.Bool("consts", false, "also declare the constants of the path and method of each route, like RoutePutJob = \"/jobs/{id}\" and MethodPutJob = http.MethodPut")
+		logging          = f.String("log", "", "structured logging of the requests of the http handlers, with their method, path, status, latency and decode errors: slog, logging with the Logger field of the -receiver or the HandlerLogger var; default none")
+		otel             = f.Bool("otel", false, "trace the http handlers with OpenTelemetry: a span of each, under the one of the request like otelhttp's, with spans of the decoding, the call and the encoding, recording the status and errors")
+		metrics          = f.String("metrics", "", "metrics of the http handlers, counting and timing their requests by handler and status class: prometheus, registered by RegisterMetrics; default none")
+		pprofLabels      = f.Bool("pprof-labels", false, "call the funcs of the http handlers in pprof.Do, labeled with the handler and encoding names, so CPU profiles attribute their time to the endpoints")
+		rateLimit        = f.String("rate-limit", "", "rate limit of the requests of the http handlers of each func, with golang.org/x/time/rate, like 10/s or 100/m:20 with a burst of 20, responding 429 with Retry-After over it; default none")
+		rateLimitKey     = f.String("rate-limit-key", "", "what -rate-limit limits the requests by: global, ip or header:<name>, like header:X-API-Key; default global")
+		breaker          = f.String("breaker", "", "circuit breaker of the calls of each func, opening after that many 5xx statuses in a row for a minute or the time after the slash, like 5/30s, responding 503 with Retry-After while open; default none")
+		idempotency      = f.Bool("idempotency", false, "replay the response stored in HandlerIdempotency to the requests repeating the Idempotency-Key header of a previous one, 409 while it is in progress, rather than calling the func again")
+		auth             = f.Bool("auth", false, "declare the Authenticator interface: the http handlers respond 401 to the requests HandlerAuthenticator fails, putting the Principal of the others in their context; funcs can be public")
+		apiKey           = f.String("api-key", "", "check the API key of the requests with the APIKeyValidator HandlerAPIKeys, responding 401 or 403: header, X-API-Key, or query, api_key, optionally followed by :<name>; default none")
+		jwt              = f.Bool("jwt", false, "read the parameters of a type named like Claims, a jwt.Claims, from the bearer token of the requests, verified with the key JWTKeyfunc returns, responding 401 if missing or invalid")
+		jwtMethods       = f.String("jwt-methods", "", "with -jwt: comma-separated list of the signing methods of the bearer tokens, like RS256; default the ones of the kind of key JWTKeyfunc returns")
+		sessions         = f.Bool("sessions", false, "declare Session and the SessionStore interface: funcs taking a *Session get the one HandlerSessions loads from the session cookie, saved once they succeed, 401 if none")
+		csrf             = f.Bool("csrf", false, "check the CSRF token of the unsafe requests, like form POSTs: the CSRFCookie cookie, SameSite Strict, issued to the GET requests having none, must match the CSRFHeader header, or 403")
+		instantiate      = f.String("instantiate", "", "comma-separated list of generic funcs to generate for, instantiated like 'Put[Job],Map[string,Job]'")
+		config           = f.String("config", "", "config file listing funcs, encodings and options; default srcdir/"+handlergen.ConfigFile+" if it exists and -func is not set")
+	)
+	flagArgs := args
+	if command != "" {
+		flagArgs = args[1:]
+	}
+	if err := f.Parse(flagArgs); err != nil {
+		return err
+	}
+	tests := ""
+	switch {
+	case *test && *xtest:
+		return errors.New("cannot parse the test files of both the package and its external test package")
+	case *test:
+		tests = "test"
+	case *xtest:
+		tests = "xtest"
+	}
+	if tests != "" && *pkg != "" {
+		return errors.New("cannot generate for the funcs of test files into another package")
+	}
+	if command == "health" && (*splitOutput || *perFile) {
+		return errors.New("cannot split the health handlers sharing their checks")
+	}
+
+	// found counts the packages generating for each func, with each.
+	var found map[string]int
+
+	// generate generates for the package of pkgArgs, the files or directory
+	// of a package; with each, as one of many, it skips the funcs the
+	// package does not declare, and the package if none is left.
+	generate := func(pkgArgs []string, each bool) error {
+		mode := command
+		if command == "crud" {
+			var err error
+			if pkgArgs, err = scaffoldCRUD(f, pkgArgs, *typeName); err != nil {
+				return err
+			}
+			mode = "" // Then generate the http handlers of the funcs.
+		}
+		configFile := *config
+		// Health handlers are generated for no func.
+		named := len(f.Funcs) > 0 || *all || *instantiate != "" || command == "health"
+		if configFile == "" && !named {
+			path := filepath.Join(loader.Dir(pkgArgs...), handlergen.ConfigFile)
+			if utils.IsFile(path) {
+				configFile = path
+			}
+		}
+		// Without -func nor config file, generate for the annotated funcs.
+		annotated := configFile == "" && !named
+		if configFile == "" && !annotated && len(*encodingPkgNames) == 0 {
+			f.Usage()
+			return ErrUsage
+		}
+
+		g := handlergen.Generator{
+			Mode:               mode,
+			By:                 credit(name, args),
+			Version:            Version(),
+			AllowMissing:       *allowMissing,
+			Strict:             *strict,
+			Verbose:            *verbose,
+			Partial:            each,
+			Tests:              tests,
+			WebSocket:          *websocket,
+			Stream:             *stream,
+			Source:             *source,
+			ContentType:        *contentType,
+			ContentDisposition: *disposition,
+			Charset:            *charset,
+			XMLHeader:          *xmlHeader,
+			XMLRoot:            *xmlRoot,
+			Negotiate:          *negotiate,
+			DefaultEncoding:    *defaultEncoding,
+			Problem:            *problem,
+			ErrorEncoder:       *errorEncoder,
+			ErrorHandler:       *errorHandler,
+			Envelope:           *envelope,
+			JSONAPI:            *jsonapi,
+			HAL:                *hal,
+			Pagination:         *pagination,
+			PageSize:           *pageSize,
+			MaxPageSize:        *maxPageSize,
+			Queue:              *queue,
+			EnvPrefix:          *envPrefix,
+			BuildConstraint:    *tagsLine,
+			Postman:            *postman,
+			OpenAPI:            *openAPI,
+			TemplateDir:        *tplDir,
+			Hooks:              split(*hooks),
+			Exclude:            split(*exclude),
+			Name:               *handlerName,
+			Unexported:         *unexported,
+			Receiver:           *receiver,
+			Interface:          *iface,
+			Values:             *values,
+			RequestID:          *requestID,
+			Manifest:           *manifest,
+			URLs:               *urls,
+			Consts:             *consts,
+			Log:                *logging,
+			Otel:               *otel,
+			Metrics:            *metrics,
+			PprofLabels:        *pprofLabels,
+			RateLimit:          *rateLimit,
+			RateLimitKey:       *rateLimitKey,
+			Breaker:            *breaker,
+			Idempotency:        *idempotency,
+			Auth:               *auth,
+			APIKey:             *apiKey,
+			JWT:                *jwt,
+			JWTMethods:         split(*jwtMethods),
+			Sessions:           *sessions,
+			CSRF:               *csrf,
+		}
+		if *header != "" {
+			data, err := ioutil.ReadFile(*header)
+			if err != nil {
+				return err
+			}
+			g.Header = string(data)
+		}
+		if *tpl != "" {
+			if err := g.SetTemplate("handler", *tpl); err != nil {
+				return err
+			}
+		}
+		g.AddFunc(f.FuncNames()...)
+		instances, err := handlergen.ParseInstances(*instantiate)
+		if err != nil {
+			return err
+		}
+		if err := g.Add(instances...); err != nil {
+			return err
+		}
+		if err := g.AddEncoding(split(*encodingPkgNames)...); err != nil {
+			return err
+		}
+		if configFile != "" {
+			c, err := handlergen.ReadConfig(configFile)
+			if err != nil {
+				return err
+			}
+			if err := c.Configure(&g); err != nil {
+				return err
+			}
+		}
+
+		// We accept either one directory or a list of files.
+		// Parse the package once.
+		if err := g.Parse(pkgArgs...); err != nil {
+			return err
+		}
+		if *all {
+			funcs := g.Eligible()
+			if len(funcs) == 0 && !each {
+				return errors.New("no func of a supported signature found")
+			}
+			g.AddFunc(funcs...)
+		}
+		if annotated {
+			funcs, err := g.Annotated()
+			if err != nil {
+				return err
+			}
+			if len(funcs) == 0 && g.Interface != "" {
+				// Generate for every method of the interface.
+				for _, name := range g.Eligible() {
+					funcs = append(funcs, handlergen.Func{Name: name})
+				}
+			}
+			if len(funcs) == 0 && !each {
+				f.Usage()
+				return ErrUsage
+			}
+			if err := g.Add(funcs...); err != nil {
+				return err
+			}
+		}
+		if command == "crud" && !annotated {
+			// The config file or -func do not tell the funcs scaffolded.
+			plan, err := g.Plan()
+			if err != nil {
+				return err
+			}
+			listed := make(map[string]bool)
+			for _, p := range plan {
+				listed[p.Func] = true
+			}
+			funcs, err := g.CRUDFuncs(*typeName)
+			if err != nil {
+				return err
+			}
+			for _, fn := range funcs {
+				if !listed[fn.Name] {
+					if err := g.Add(fn); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		if each && command != "health" {
+			plan, err := g.Plan()
+			if err != nil {
+				return err
+			}
+			for _, p := range plan {
+				found[strings.SplitN(p.Func, "[", 2)[0]]++
+			}
+			if len(plan) == 0 {
+				return nil // Nothing to generate in this package.
+			}
+		}
+
+		if *list {
+			return printPlan(&g)
+		}
+
+		if *pkg != "" {
+			abs, err := filepath.Abs(*pkg)
+			if err != nil {
+				return err
+			}
+			g.Package = strings.Replace(filepath.Base(abs), "-", "_", -1)
+			if f.writes() {
+				if err := os.MkdirAll(*pkg, 0755); err != nil {
+					return err
+				}
+			}
+		}
+
+		// Write to file.
+		kind := "handlers"
+		switch g.Mode {
+		case "consumer":
+			kind = "consumers"
+		case "command":
+			kind = "commands"
+		case "job":
+			kind = "jobs"
+		case "health":
+			kind = "health"
+		}
+		dir := loader.Dir(pkgArgs...)
+		if *pkg != "" {
+			dir = *pkg
+		}
+		write := func(outputName string) error {
+			if *splitOutput {
+				if outputName == "-" {
+					return errors.New("cannot write -split output to stdout")
+				}
+				return writeSplit(f, &g, outputName)
+			}
+			return writeFile(f, outputName, &g, g.Render)
+		}
+		if *perFile {
+			if f.Output != "" || g.Output != "" {
+				return errors.New("cannot set the output file with -per-file")
+			}
+			err = writePerFile(&g, func(file string) error {
+				base := strings.TrimSuffix(filepath.Base(file), ".go")
+				if strings.HasSuffix(base, "_test") {
+					// Keep the code of the funcs of a test file in a test file.
+					return write(filepath.Join(dir, strings.TrimSuffix(base, "_test")+"_"+kind+"_test.go"))
+				}
+				return write(filepath.Join(dir, base+"_"+kind+".go"))
+			})
+		} else {
+			outputName := f.Output
+			if outputName == "" {
+				outputName = g.Output
+			}
+			if outputName == "" {
+				outputName = filepath.Join(dir, "generated_"+kind+".go")
+				if tests != "" {
+					outputName = filepath.Join(dir, "generated_"+kind+"_test.go")
+				}
+			}
+			err = write(outputName)
+		}
+		if err != nil {
+			return err
+		}
+		if g.Postman != "" {
+			if err := writeExport(f, g.Postman, "Postman collection", g.RenderPostman); err != nil {
+				return err
+			}
+		}
+		if g.OpenAPI != "" {
+			if err := writeExport(f, g.OpenAPI, "OpenAPI spec", g.RenderOpenAPI); err != nil {
+				return err
+			}
+		}
+		if f.DryRun && *all {
+			for _, skipped := range g.Skipped() {
+				fmt.Printf("%s: skipped %s: %s\n", skipped.Pos, skipped.Func, skipped.Reason)
+			}
+		}
+		return nil
+	}
+
+	// Generate for each package of the ./... patterns or directories, if
+	// many, skipping the ones without any func to generate for.
+	dirs, err := packageDirs(f.Args())
+	if err != nil {
+		return err
+	}
+	if dirs != nil && (f.Output != "" || *pkg != "") {
+		return errors.New("cannot generate the code of several packages into one -output or -pkg")
+	}
+	if dirs != nil && *postman != "" {
+		return errors.New("cannot export the routes of several packages into one -postman collection")
+	}
+	if dirs != nil && *openAPI != "" {
+		return errors.New("cannot describe the routes of several packages in one -openapi spec")
+	}
+	if dirs != nil && command == "crud" {
+		return errors.New("cannot scaffold the funcs of several packages")
+	}
+	if *watchFiles && command == "crud" {
+		return errors.New("cannot -watch while scaffolding funcs")
+	}
+	run := func() error {
+		if dirs == nil {
+			return generate(f.Args(), false)
+		}
+		found = make(map[string]int)
+		for _, dir := range dirs {
+			if err := generate([]string{dir}, true); err != nil {
+				return err
+			}
+		}
+		// Each package skips the -func funcs it does not declare: fail on
+		// the ones none declares.
+		for _, name := range f.FuncNames() {
+			if handlergen.IsPattern(name) || found[name] > 0 {
+				continue
+			}
+			if !*allowMissing || *strict {
+				return fmt.Errorf("func %s not found in any package", name)
+			}
+			log.Printf("warning: func %s not found in any package, skipped", name)
+		}
+		return nil
+	}
+	if *watchFiles {
+		if serving {
+			return errors.New("cannot -watch through a server")
+		}
+		if !f.writes() {
+			return errors.New("cannot -watch with -check, -diff or -dry-run")
+		}
+		if dirs == nil {
+			dirs = []string{loader.Dir(f.Args()...)}
+		}
+		return watch(dirs, run)
+	}
+	if err := run(); err != nil {
+		return err
+	}
+	return f.Stale()
+}
+
+// packageDirs returns the directories of the packages args name when they
+// are many: directories, or patterns like ./... matching the directories
+// holding Go files under ./, but testdata and vendor. It is nil for a single
+// directory or a list of files.
+func packageDirs(args []string) ([]string, error) {
+	many := len(args) > 1
+	for _, arg := range args {
+		if arg == "..." || strings.HasSuffix(arg, "/...") {
+			many = true
+		} else if !utils.IsDirectory(arg) {
+			return nil, nil // Files of a package.
+		}
+	}
+	if !many {
+		return nil, nil
+	}
+	var dirs []string
+	seen := make(map[string]bool)
+	for _, arg := range args {
+		if arg != "..." && !strings.HasSuffix(arg, "/...") {
+			if !seen[arg] {
+				seen[arg] = true
+				dirs = append(dirs, arg)
+			}
+			continue
+		}
+		root := strings.TrimSuffix(strings.TrimSuffix(arg, "..."), "/")
+		if root == "" {
+			root = "."
+		}
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				name := info.Name()
+				if path != root && (name == "testdata" || name == "vendor" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			dir := filepath.Dir(path)
+			if strings.HasSuffix(path, ".go") && !strings.HasSuffix(path, "_test.go") && !seen[dir] {
+				seen[dir] = true
+				dirs = append(dirs, dir)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return dirs, nil
+}
+
+// writeFile writes what render renders to outputName, set as the Output of g,
+// or to stdout if outputName is -.
+func writeFile(f *Flags, outputName string, g *handlergen.Generator, render func(w io.Writer) error) error {
+	g.Output = outputName
+	var src bytes.Buffer
+	if err := render(&src); err != nil {
+		return err
+	}
+	return f.write(outputName, src.Bytes())
+}
+
+// writeSplit writes the code of each encoding to outputName suffixed by the
+// encoding pkg name, and RegisterHandlers, if any route is set, to outputName.
+func writeSplit(f *Flags, g *handlergen.Generator, outputName string) error {
+	plan, err := g.Plan()
+	if err != nil {
+		return err
+	}
+	var encodings []string
+	seen := make(map[string]bool)
+	routes := false
+	for _, p := range plan {
+		for _, encoding := range p.Encodings {
+			if !seen[encoding] {
+				seen[encoding] = true
+				encodings = append(encodings, encoding)
+			}
+		}
+		routes = routes || p.Route != ""
+	}
+	base, suffix := strings.TrimSuffix(outputName, ".go"), ".go"
+	if strings.HasSuffix(base, "_test") {
+		base, suffix = strings.TrimSuffix(base, "_test"), "_test.go"
+	}
+	for _, encoding := range encodings {
+		name, err := g.EncodingAlias(encoding)
+		if err != nil {
+			return err
+		}
+		err = writeFile(f, base+"_"+name+suffix, g, func(w io.Writer) error {
+			return g.RenderEncoding(w, encoding)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if !routes {
+		return nil
+	}
+	return writeFile(f, outputName, g, g.RenderRoutes)
+}
+
+// writeExport writes what render renders of the routes, like the Postman
+// collection, to name, never merged: -merge only merges Go code.
+func writeExport(f *Flags, name, what string, render func(w io.Writer) error) error {
+	var src bytes.Buffer
+	if err := render(&src); err != nil {
+		return err
+	}
+	if f.DryRun {
+		fmt.Printf("%s: %s\n", name, what)
+		return nil
+	}
+	merge := f.Merge
+	f.Merge = false
+	defer func() { f.Merge = merge }()
+	return f.write(name, src.Bytes())
+}
+
+// writePerFile calls write with each source file declaring funcs g generates
+// for, g only generating for its funcs then.
+func writePerFile(g *handlergen.Generator, write func(file string) error) error {
+	plan, err := g.Plan()
+	if err != nil {
+		return err
+	}
+	var files []string
+	seen := make(map[string]bool)
+	routed := make(map[string]bool)
+	for _, p := range plan {
+		if !seen[p.File] {
+			seen[p.File] = true
+			files = append(files, p.File)
+		}
+		if p.Route != "" {
+			routed[p.File] = true
+		}
+	}
+	if len(routed) > 1 {
+		return errors.New("cannot register the routes of funcs of several source files with -per-file")
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		g.File = file
+		if err := write(file); err != nil {
+			return err
+		}
+	}
+	g.File = ""
+	return nil
+}
+
+// printPlan prints what g would generate, one func per line.
+func printPlan(g *handlergen.Generator) error {
+	plan, err := g.Plan()
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	for _, p := range plan {
+		fmt.Fprintf(w, "%s(%s)\t%s", p.Func, p.T, strings.Join(p.Encodings, ","))
+		if p.Route != "" {
+			fmt.Fprintf(w, "\t%s", p.Route)
+		}
+		fmt.Fprintf(w, "\n")
+	}
+	return w.Flush()
+}
@@ -0,0 +1,41 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFuncNotFoundInAnyPackage(t *testing.T) {
+	dir := t.TempDir()
+	for name, src := range map[string]string{
+		"go.mod": "module jobs\n\ngo 1.22\n",
+		"a/a.go": "package a\n\nfunc GetJob(id string) (string, int) { return id, 200 }\n",
+		"b/b.go": "package b\n\nfunc ListJobs() ([]string, int) { return nil, 200 }\n",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dirs := []string{filepath.Join(dir, "a"), filepath.Join(dir, "b")}
+	for _, test := range []struct {
+		args []string
+		fail bool
+	}{
+		{[]string{"-func", "GetJob,ListJobs"}, false},
+		{[]string{"-func", "GetJob,Nope"}, true},
+		{[]string{"-func", "GetJob,Nope", "-allow-missing"}, false},
+		{[]string{"-func", "GetJob,Nope", "-allow-missing", "-strict"}, true},
+		{[]string{"-func", "Get*,Nope*"}, false},
+	} {
+		args := append(append(test.args, "-encoding", "encoding/json", "-n"), dirs...)
+		if err := Handler("handler", args); (err != nil) != test.fail {
+			t.Errorf("handler %v returns %v, want an error: %v", args, err, test.fail)
+		}
+	}
+}
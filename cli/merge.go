@@ -0,0 +1,361 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/imports"
+)
+
+// merge returns src merged into old, a file generated by a previous run: the
+// declarations of old that src does not declare again are kept, along with
+// the imports they need and the "Code generated by" lines of the runs that
+// generated them. The funcs registering handlers on a mux, like
+// RegisterHandlers, and the ones returning a slice literal, like Routes,
+// combine the statements and elements of the runs, and const and var blocks
+// their specs, matched by name. Declarations are sorted by name, so merging
+// the code of several runs gives the same file whatever the order they run
+// in.
+func merge(name string, old, src []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	oldFile, err := parser.ParseFile(fset, name, old, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("cannot merge into %s: %s", name, err)
+	}
+	srcFile, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	// The specs of the const and var blocks, by doc comment and token.
+	blocks := make(map[string]map[string]string)
+	decls := make(map[string]combined)
+	paths := make(map[string]string)  // Import specs by path.
+	header := make(map[string]string) // "Code generated by" lines, by command.
+	var preamble []string             // Lines of src before the package clause.
+	for _, f := range []struct {
+		file *ast.File
+		src  []byte
+	}{{oldFile, old}, {srcFile, src}} {
+		base := fset.File(f.file.Pos()).Base()
+		offset := func(pos token.Pos) int { return int(pos) - base }
+		for _, line := range strings.Split(strings.TrimSuffix(string(f.src[:offset(f.file.Package)]), "\n"), "\n") {
+			if strings.HasPrefix(line, "// Code generated ") {
+				// The line of a run with another version of the tool
+				// is replaced.
+				by := line
+				if quoted := strings.SplitN(line, `"`, 3); len(quoted) == 3 {
+					by = quoted[1]
+				}
+				header[by] = line
+			}
+			if f.file == srcFile {
+				preamble = append(preamble, line)
+			}
+		}
+		for _, spec := range f.file.Imports {
+			path, _ := strconv.Unquote(spec.Path.Value)
+			paths[path] = importSpec(spec)
+		}
+		text := func(start, end token.Pos) string { return string(f.src[offset(start):offset(end)]) }
+		for _, decl := range f.file.Decls {
+			if decl, ok := decl.(*ast.GenDecl); ok && decl.Tok == token.IMPORT {
+				continue
+			}
+			start := decl.Pos()
+			if doc := declDoc(decl); doc != nil {
+				start = doc.Pos()
+			}
+			if decl, ok := decl.(*ast.GenDecl); ok && splits(decl) {
+				// The specs of src replace the ones of old of the same
+				// names, in any block.
+				key := decl.Tok.String()
+				if decl.Doc != nil {
+					key = text(decl.Doc.Pos(), decl.Doc.End()) + "\n" + key
+				}
+				if blocks[key] == nil {
+					blocks[key] = make(map[string]string)
+				}
+				for _, spec := range decl.Specs {
+					name := specKey(spec)
+					for _, specs := range blocks {
+						delete(specs, name)
+					}
+					blocks[key][name] = specText(spec, text)
+				}
+				continue
+			}
+			key, d := declKey(decl), combined{src: text(start, decl.End())}
+			if fn, ok := decl.(*ast.FuncDecl); ok {
+				if fn.Name.Name == "init" && fn.Recv == nil {
+					// A file can have many, they are all kept.
+					key += " " + d.src
+				}
+				d = combinable(fn, d.src, text)
+				if old, ok := decls[key]; ok && old.parts != nil && d.parts != nil && old.sep == d.sep {
+					// The statements or elements of both runs are kept.
+					d.parts = union(old.parts, d.parts)
+				}
+			}
+			// Declarations of src replace the ones of old.
+			decls[key] = d
+		}
+	}
+	for key, specs := range blocks {
+		if len(specs) == 0 {
+			continue
+		}
+		names := sortedKeys(specs)
+		var src strings.Builder
+		doc, tok := "", key
+		if i := strings.LastIndex(key, "\n"); i >= 0 {
+			doc, tok = key[:i+1], key[i+1:]
+		}
+		fmt.Fprintf(&src, "%s%s (\n", doc, tok)
+		for _, name := range names {
+			fmt.Fprintf(&src, "%s\n", specs[name])
+		}
+		src.WriteString(")")
+		decls[names[0]] = combined{src: src.String()}
+	}
+
+	var buf bytes.Buffer
+	credited := false
+	for _, line := range preamble {
+		if !strings.HasPrefix(line, "// Code generated ") {
+			fmt.Fprintf(&buf, "%s\n", line) // Like a license or a //go:build line.
+			continue
+		}
+		if !credited {
+			// Credit every run where src credits its own.
+			for _, by := range sortedKeys(header) {
+				fmt.Fprintf(&buf, "%s\n", header[by])
+			}
+			credited = true
+		}
+	}
+	fmt.Fprintf(&buf, "package %s\n\n", srcFile.Name.Name)
+	if len(paths) > 0 {
+		// Like the generated code, import the standard pkgs first.
+		var std, other []string
+		for _, path := range sortedKeys(paths) {
+			if strings.Contains(strings.SplitN(path, "/", 2)[0], ".") {
+				other = append(other, paths[path])
+			} else {
+				std = append(std, paths[path])
+			}
+		}
+		fmt.Fprintf(&buf, "import (\n")
+		for _, spec := range std {
+			fmt.Fprintf(&buf, "\t%s\n", spec)
+		}
+		if len(std) > 0 && len(other) > 0 {
+			fmt.Fprintf(&buf, "\n")
+		}
+		for _, spec := range other {
+			fmt.Fprintf(&buf, "\t%s\n", spec)
+		}
+		fmt.Fprintf(&buf, ")\n")
+	}
+	for _, key := range sortedKeys(decls) {
+		fmt.Fprintf(&buf, "\n%s\n", decls[key].String())
+	}
+	// Remove the imports only the replaced declarations needed.
+	return imports.Process(name, buf.Bytes(), nil)
+}
+
+// combined is a declaration of a merged file. The funcs whose body combines
+// across runs are split into their head, the statements or elements of the
+// body and its tail.
+type combined struct {
+	src        string
+	head, tail string
+	parts      []string
+	sep        string // after each part, a comma for the elements of a literal
+}
+
+// String returns the source of d.
+func (d combined) String() string {
+	if d.parts == nil {
+		return d.src
+	}
+	var src strings.Builder
+	src.WriteString(d.head)
+	for _, part := range d.parts {
+		fmt.Fprintf(&src, "\n%s%s", part, d.sep)
+	}
+	src.WriteString(d.tail)
+	return src.String()
+}
+
+// combinable returns fn, whose source with its doc comment is src, split
+// into its head, parts and tail if its body combines with the one of the
+// same func of another run: a func taking a *http.ServeMux made of calls
+// only, like mux.HandleFunc, or a func only returning a slice literal.
+func combinable(fn *ast.FuncDecl, src string, text func(start, end token.Pos) string) combined {
+	d := combined{src: src}
+	if fn.Body == nil || len(fn.Body.List) == 0 {
+		return d
+	}
+	start := fn.Pos()
+	if fn.Doc != nil {
+		start = fn.Doc.Pos()
+	}
+	if ret, ok := fn.Body.List[0].(*ast.ReturnStmt); ok && len(fn.Body.List) == 1 && len(ret.Results) == 1 {
+		lit, ok := ret.Results[0].(*ast.CompositeLit)
+		if !ok {
+			return d
+		}
+		if t, ok := lit.Type.(*ast.ArrayType); !ok || t.Len != nil {
+			return d
+		}
+		d.parts = []string{}
+		for _, elt := range lit.Elts {
+			d.parts = append(d.parts, text(elt.Pos(), elt.End()))
+		}
+		d.head, d.tail, d.sep = text(start, lit.Lbrace+1), "\n}\n}", ","
+		return d
+	}
+	mux := false
+	for _, field := range fn.Type.Params.List {
+		if star, ok := field.Type.(*ast.StarExpr); ok {
+			if sel, ok := star.X.(*ast.SelectorExpr); ok && sel.Sel.Name == "ServeMux" {
+				mux = true
+			}
+		}
+	}
+	if !mux {
+		return d
+	}
+	var parts []string
+	for _, stmt := range fn.Body.List {
+		if _, ok := stmt.(*ast.ExprStmt); !ok {
+			return d
+		}
+		parts = append(parts, text(stmt.Pos(), stmt.End()))
+	}
+	d.head, d.parts, d.tail = text(start, fn.Body.Lbrace+1), parts, "\n}"
+	return d
+}
+
+// union returns the parts of a and b, without duplicates, sorted.
+func union(a, b []string) []string {
+	seen := make(map[string]bool)
+	parts := []string{}
+	for _, part := range append(append([]string(nil), a...), b...) {
+		if !seen[part] {
+			seen[part] = true
+			parts = append(parts, part)
+		}
+	}
+	sort.Strings(parts)
+	return parts
+}
+
+// splits reports whether the specs of decl are merged one by one, those of
+// a const or var block. A const block whose specs repeat the previous value,
+// like with iota, is kept whole since its order matters.
+func splits(decl *ast.GenDecl) bool {
+	if !decl.Lparen.IsValid() || (decl.Tok != token.CONST && decl.Tok != token.VAR) {
+		return false
+	}
+	for _, spec := range decl.Specs {
+		if spec, ok := spec.(*ast.ValueSpec); ok && decl.Tok == token.CONST && len(spec.Values) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// specKey returns what identifies spec in a block, the names it declares.
+func specKey(spec ast.Spec) string {
+	var names []string
+	if spec, ok := spec.(*ast.ValueSpec); ok {
+		for _, name := range spec.Names {
+			names = append(names, name.Name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// specText returns the source of spec, with its doc and line comments.
+func specText(s ast.Spec, text func(start, end token.Pos) string) string {
+	spec := s.(*ast.ValueSpec)
+	start, end := spec.Pos(), spec.End()
+	if spec.Doc != nil {
+		start = spec.Doc.Pos()
+	}
+	if spec.Comment != nil {
+		end = spec.Comment.End()
+	}
+	return text(start, end)
+}
+
+// importSpec returns how spec is written in an import block, like
+// "encoding/json" or yaml "gopkg.in/yaml.v3".
+func importSpec(spec *ast.ImportSpec) string {
+	if spec.Name != nil {
+		return spec.Name.Name + " " + spec.Path.Value
+	}
+	return spec.Path.Value
+}
+
+// declDoc returns the doc comment of decl, if any.
+func declDoc(decl ast.Decl) *ast.CommentGroup {
+	switch decl := decl.(type) {
+	case *ast.FuncDecl:
+		return decl.Doc
+	case *ast.GenDecl:
+		return decl.Doc
+	}
+	return nil
+}
+
+// declKey returns what identifies decl in a file, like PutJobHandlerJSON or
+// Server.PutJobHandlerJSON for a method.
+func declKey(decl ast.Decl) string {
+	switch decl := decl.(type) {
+	case *ast.FuncDecl:
+		if decl.Recv == nil || len(decl.Recv.List) != 1 {
+			return decl.Name.Name
+		}
+		t := decl.Recv.List[0].Type
+		if star, ok := t.(*ast.StarExpr); ok {
+			t = star.X
+		}
+		if id, ok := t.(*ast.Ident); ok {
+			return id.Name + "." + decl.Name.Name
+		}
+		return decl.Name.Name
+	case *ast.GenDecl:
+		var names []string
+		for _, spec := range decl.Specs {
+			switch spec := spec.(type) {
+			case *ast.TypeSpec:
+				names = append(names, spec.Name.Name)
+			case *ast.ValueSpec:
+				for _, name := range spec.Names {
+					names = append(names, name.Name)
+				}
+			}
+		}
+		return strings.Join(names, ",")
+	}
+	return ""
+}
+
+// sortedKeys returns the keys of m, sorted.
+func sortedKeys[V any](m map[string]V) []string {
+	var keys []string
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,180 @@
+package cli
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// getJob is the code of a run with a route, -manifest and -consts.
+const getJob = `// Code generated by "handler -func GetJob -route 'GET /jobs/{id}' -manifest -consts"; DO NOT EDIT
+
+package jobs
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+func GetJobHandlerJSON(w http.ResponseWriter, r *http.Request) {
+	resp, status := GetJob(r.PathValue("id"))
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// RegisterHandlers registers on mux the handlers of the funcs given a route.
+func RegisterHandlers(mux *http.ServeMux) {
+	mux.HandleFunc("GET /jobs/{id}", GetJobHandlerJSON)
+}
+
+// Routes returns the routes RegisterHandlers registers.
+func Routes() []RouteInfo {
+	return []RouteInfo{
+		{Method: "GET", Path: "/jobs/{id}", Handler: "GetJobHandlerJSON"},
+	}
+}
+
+// Paths and methods of the routes, to refer to them.
+const (
+	RouteGetJob  = "/jobs/{id}"
+	MethodGetJob = http.MethodGet
+)
+`
+
+// jobsPkg is a package whose funcs two runs generate for, each with a
+// route, sharing a file with -merge.
+var jobsPkg = map[string]string{
+	"go.mod": "module jobs\n\ngo 1.22\n",
+	"jobs.go": `package jobs
+
+type Job struct{ ID string }
+
+func GetJob(id string) (Job, int) { return Job{ID: id}, 200 }
+
+func ListJobs() ([]Job, int) { return nil, 200 }
+`,
+	"get.yaml": `encodings: [encoding/json]
+funcs:
+  - name: GetJob
+    route: GET /jobs/{id}
+    params: {id: path}
+`,
+	"list.yaml": `encodings: [encoding/json]
+funcs:
+  - name: ListJobs
+    route: GET /jobs
+`,
+}
+
+func TestMergeRoutes(t *testing.T) {
+	// run runs handler with each config in a new copy of jobsPkg, returning
+	// its directory and the file generated.
+	run := func(configs ...string) (string, []byte) {
+		t.Helper()
+		dir := t.TempDir()
+		for name, src := range jobsPkg {
+			if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, config := range configs {
+			args := []string{"-merge", "-manifest", "-consts", "-urls", "-config", filepath.Join(dir, config), dir}
+			if err := Handler("handler", args); err != nil {
+				t.Fatalf("handler %s: %s", strings.Join(args, " "), err)
+			}
+		}
+		src, err := ioutil.ReadFile(filepath.Join(dir, "generated_handlers.go"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return dir, src
+	}
+	dir, src := run("get.yaml", "list.yaml")
+	for _, want := range []string{
+		`mux.HandleFunc("GET /jobs/{id}", GetJobHandlerJSON)`,
+		`mux.HandleFunc("GET /jobs", ListJobsHandlerJSON)`,
+		`{Method: "GET", Path: "/jobs/{id}", Handler: "GetJobHandlerJSON"`,
+		`{Method: "GET", Path: "/jobs", Handler: "ListJobsHandlerJSON"`,
+		`RouteGetJob    = "/jobs/{id}"`,
+		`RouteListJobs  = "/jobs"`,
+		`func GetJobURL(id string) string`,
+		`func ListJobsURL() string`,
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("merged file does not hold %s:\n%s", want, src)
+		}
+	}
+	packageNames(t, src)
+	// The "Code generated by" lines tell the other directory.
+	_, reversed := run("list.yaml", "get.yaml")
+	if code := func(src []byte) []byte { return src[bytes.Index(src, []byte("package")):] }; !bytes.Equal(code(reversed), code(src)) {
+		t.Errorf("merging the runs in the other order gives:\n%s\nwant:\n%s", reversed, src)
+	}
+	if _, err := exec.LookPath("go"); err == nil {
+		cmd := exec.Command("go", "vet", ".")
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Errorf("merged file does not build: %s\n%s\n%s", err, out, src)
+		}
+	}
+}
+
+func TestMergeSameRun(t *testing.T) {
+	merged, err := merge("generated_handlers.go", []byte(getJob), []byte(getJob))
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := merge("generated_handlers.go", merged, []byte(getJob))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != string(merged) {
+		t.Errorf("merging a run again gives:\n%s\nwant:\n%s", again, merged)
+	}
+	if n := strings.Count(string(merged), "mux.HandleFunc"); n != 1 {
+		t.Errorf("merging a run into its own code registers %d routes, want 1:\n%s", n, merged)
+	}
+	if got := packageNames(t, merged); len(got) != 5 {
+		t.Errorf("merged file declares %v, want the handler, RegisterHandlers, Routes and the 2 consts", got)
+	}
+}
+
+// packageNames returns the names src declares at the package level, failing
+// on the ones declared twice.
+func packageNames(t *testing.T, src []byte) []string {
+	t.Helper()
+	file, err := parser.ParseFile(token.NewFileSet(), "", src, 0)
+	if err != nil {
+		t.Fatalf("merged file does not parse: %s\n%s", err, src)
+	}
+	var names []string
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if seen[name] {
+			t.Errorf("%s is declared twice:\n%s", name, src)
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			add(decl.Name.Name)
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				if spec, ok := spec.(*ast.ValueSpec); ok {
+					for _, name := range spec.Names {
+						add(name.Name)
+					}
+				}
+			}
+		}
+	}
+	return names
+}
@@ -0,0 +1,175 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/azr/generators/loader"
+)
+
+// ServerEnv is the environment variable naming the socket of a handler
+// serve server that runs handler: set, the runs are sent to the server,
+// keeping the packages loaded, if it is listening, and run here otherwise.
+const ServerEnv = "HANDLER_SERVER"
+
+// serving is set by serve, for the runs it serves not to exit.
+var serving bool
+
+// errVersion is returned by Flags.Parse when serving, having printed the
+// version.
+var errVersion = errors.New("version printed")
+
+// request is a run sent to the server.
+type request struct {
+	Dir  string   // Working directory.
+	Name string   // How the command was invoked, like "handler".
+	Args []string // Arguments of the command.
+}
+
+// response is what a run printed and returned.
+type response struct {
+	Stdout []byte
+	Stderr []byte
+	Err    string `json:",omitempty"`
+	Usage  bool   `json:",omitempty"` // The run returned ErrUsage.
+}
+
+// forward sends the run of name with args to the server listening on
+// socket, printing what it printed and returning its error. It reports
+// whether the server ran it, or the run is to be made here.
+func forward(socket, name string, args []string) (bool, error) {
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return false, nil // No server.
+	}
+	defer conn.Close()
+	dir, err := os.Getwd()
+	if err != nil {
+		return false, nil
+	}
+	if err := json.NewEncoder(conn).Encode(request{Dir: dir, Name: name, Args: args}); err != nil {
+		return true, fmt.Errorf("server %s: %s", socket, err)
+	}
+	var resp response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return true, fmt.Errorf("server %s: %s", socket, err)
+	}
+	os.Stdout.Write(resp.Stdout)
+	os.Stderr.Write(resp.Stderr)
+	switch {
+	case resp.Usage:
+		return true, ErrUsage
+	case resp.Err != "":
+		return true, errors.New(resp.Err)
+	}
+	return true, nil
+}
+
+// serve listens on socket for runs of handler, keeping the packages they
+// load, until interrupted. The runs are made one at a time: each changes the
+// working directory and the output of the process.
+func serve(socket string) error {
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		if conn, dialErr := net.Dial("unix", socket); dialErr == nil {
+			conn.Close()
+			return fmt.Errorf("a server is already listening on %s", socket)
+		}
+		// Left by a server that did not stop cleanly.
+		os.Remove(socket)
+		if l, err = net.Listen("unix", socket); err != nil {
+			return err
+		}
+	}
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-stop
+		l.Close() // Removes the socket.
+	}()
+
+	serving = true
+	loader.Cache()
+	log.Printf("serving on %s", socket)
+	for {
+		conn, err := l.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := serveConn(conn); err != nil {
+			log.Print(err)
+		}
+	}
+}
+
+// serveConn makes the run conn sends and sends back its response.
+func serveConn(conn net.Conn) error {
+	defer conn.Close()
+	var req request
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		return fmt.Errorf("reading request: %s", err)
+	}
+	var resp response
+	err := run(req, &resp)
+	switch {
+	case err == ErrUsage:
+		resp.Usage = true
+	case err == errVersion:
+	case err != nil:
+		resp.Err = err.Error()
+	}
+	return json.NewEncoder(conn).Encode(resp)
+}
+
+// run makes the run of req from its directory, capturing what it prints
+// into resp.
+func run(req request, resp *response) (err error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	if err := os.Chdir(req.Dir); err != nil {
+		return err
+	}
+	defer os.Chdir(wd)
+
+	stdout, err := ioutil.TempFile("", "handler-stdout")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(stdout.Name())
+	defer stdout.Close()
+	stderr, err := ioutil.TempFile("", "handler-stderr")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(stderr.Name())
+	defer stderr.Close()
+	oldStdout, oldStderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = stdout, stderr
+	log.SetOutput(stderr)
+	defer func() {
+		os.Stdout, os.Stderr = oldStdout, oldStderr
+		log.SetOutput(oldStderr)
+		resp.Stdout, _ = ioutil.ReadFile(stdout.Name())
+		resp.Stderr, _ = ioutil.ReadFile(stderr.Name())
+	}()
+
+	defer func() {
+		// Keep serving the other runs.
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return Handler(req.Name, req.Args)
+}
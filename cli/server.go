@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/azr/generators/handlergen"
+)
+
+// Server runs server with args, name being how it was invoked, like
+// "generators server": it writes the main.go of a command serving the
+// handlers RegisterHandlers registers, generated by handler, and the health
+// handlers of RegisterHealthHandlers, if any.
+func Server(name string, args []string) error {
+	f := NewFlags(name, "output file name, or - for stdout; default moduledir/cmd/<name>/main.go",
+		name+" [flags] [directory] # For the package generated into with routes",
+		"For more information, see: http://godoc.org/github.com/azr/generators/cmd/generators",
+	)
+	var (
+		cmdName = f.String("name", "", "name of the command, its directory under cmd; default the name of the package")
+		header  = f.String("header", "", "path to a file, like a license, printed at the top of the generated file before the \"Code generated\" line")
+	)
+	if err := f.Parse(args); err != nil {
+		return err
+	}
+	if f.Funcs != "" {
+		return errors.New("cannot serve funcs: the command serves the handlers of the package")
+	}
+
+	g := handlergen.Generator{
+		By:      credit(name, args),
+		Version: Version(),
+	}
+	if *header != "" {
+		data, err := ioutil.ReadFile(*header)
+		if err != nil {
+			return err
+		}
+		g.Header = string(data)
+	}
+	if err := g.Parse(f.Args()...); err != nil {
+		return err
+	}
+	if *cmdName == "" {
+		*cmdName = g.PackageName()
+	}
+	outputName := f.Output
+	if outputName == "" {
+		dir, err := g.ModuleDir()
+		if err != nil {
+			return err
+		}
+		outputName = filepath.Join(dir, "cmd", *cmdName, "main.go")
+	}
+	var src bytes.Buffer
+	if err := g.RenderServer(&src, *cmdName); err != nil {
+		return err
+	}
+	if outputName != "-" && f.writes() {
+		if err := os.MkdirAll(filepath.Dir(outputName), 0755); err != nil {
+			return err
+		}
+	}
+	if err := f.write(outputName, src.Bytes()); err != nil {
+		return err
+	}
+	return f.Stale()
+}
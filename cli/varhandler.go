@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/azr/generators/loader"
+	"github.com/azr/generators/varhandlergen"
+)
+
+// VarHandler runs varhandler with args, name being how it was invoked, like
+// "varhandler" or "generators varhandler".
+func VarHandler(name string, args []string) error {
+	f := NewFlags(name, "output file name, or - for stdout without writing the helpers;\n\tdefault for multiple funcs: pkgdir/generated_varhandlers.go\n\tdefault for one func: pkgdir/<toLower(funcName)>_handler_generated.go",
+		name+" [flags] -func F [directory]",
+		name+" [flags] -func F files... # Must be a single package",
+		"For more information, see: http://godoc.org/github.com/azr/generators/varhandler",
+	)
+	if err := f.Parse(args); err != nil {
+		return err
+	}
+	if len(f.Funcs) == 0 {
+		f.Usage()
+		return ErrUsage
+	}
+	funcs := f.FuncNames()
+
+	g := varhandlergen.Generator{
+		By:      credit(name, args),
+		Version: Version(),
+	}
+	g.AddFunc(funcs...)
+
+	// We accept either one directory or a list of files.
+	// Parse the package once.
+	if err := g.Parse(f.Args()...); err != nil {
+		return err
+	}
+	var src bytes.Buffer
+	if err := g.Render(&src); err != nil {
+		return err
+	}
+
+	// Write to file.
+	dir := loader.Dir(f.Args()...)
+	outputName := f.Output
+	if outputName == "" {
+		if len(funcs) == 1 {
+			outputName = filepath.Join(dir, fmt.Sprintf("%s_handler_generated.go", strings.ToLower(funcs[0])))
+		} else {
+			outputName = filepath.Join(dir, "generated_varhandlers.go")
+		}
+	}
+	if err := f.write(outputName, src.Bytes()); err != nil {
+		return err
+	}
+	if outputName == "-" || !f.writes() {
+		return f.Stale()
+	}
+
+	// copy helper file to pkg
+	return g.WriteHelpers(dir)
+}
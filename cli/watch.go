@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/azr/generators/handlergen"
+)
+
+// watchInterval is how often watch looks for changes.
+const watchInterval = 500 * time.Millisecond
+
+// watch calls run, then again each time a Go file, template or config file
+// of the dirs changes, until interrupted. Errors of run are logged rather
+// than stopping it, to be fixed by the next change.
+func watch(dirs []string, run func() error) error {
+	for {
+		// Look before running, not to miss a change made meanwhile; the
+		// files run writes trigger one more run, which writes nothing.
+		before := snapshot(dirs)
+		if err := run(); err != nil {
+			log.Print(err)
+		}
+		for snapshot(dirs) == before {
+			time.Sleep(watchInterval)
+		}
+		log.Printf("files changed, generating again")
+	}
+}
+
+// snapshot returns the names, sizes and modification times of the files of
+// the dirs watch watches.
+func snapshot(dirs []string) string {
+	var b strings.Builder
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if entry.IsDir() || !strings.HasSuffix(name, ".go") && !strings.HasSuffix(name, ".gotpl") && name != handlergen.ConfigFile {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(&b, "%s %d %d\n", filepath.Join(dir, name), info.Size(), info.ModTime().UnixNano())
+		}
+	}
+	return b.String()
+}
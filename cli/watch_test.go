@@ -0,0 +1,33 @@
+package cli
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCheckWatched(t *testing.T) {
+	dir := t.TempDir()
+	for name, src := range jobsPkg {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	args := []string{"-config", filepath.Join(dir, "get.yaml"), dir}
+	// The watch keeps running until the tests end.
+	go Handler("handler", append([]string{"-watch"}, args...))
+	output := filepath.Join(dir, "generated_handlers.go")
+	for deadline := time.Now().Add(10 * time.Second); ; time.Sleep(50 * time.Millisecond) {
+		if src, err := ioutil.ReadFile(output); err == nil && bytes.HasSuffix(src, []byte("}\n")) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("handler -watch did not write %s", output)
+		}
+	}
+	if err := Handler("handler", append([]string{"-check"}, args...)); err != nil {
+		t.Errorf("handler -check after handler -watch: %s", err)
+	}
+}
@@ -0,0 +1,74 @@
+// Generators bundles the handler, varhandler and server generators in one binary.
+//
+//  generators handler [flags] -func F -encoding 'encoding/json' [directory]
+//  generators handler consumer|command|job|health [flags] -func F -encoding 'encoding/json' [directory]
+//  generators handler crud [flags] -type T -encoding 'encoding/json' [directory]
+//  generators varhandler [flags] -func F [directory]
+//  generators server [flags] [directory]
+//
+// Each subcommand takes the flags of the command of the same name, see
+// http://godoc.org/github.com/azr/generators/handler and
+// http://godoc.org/github.com/azr/generators/varhandler.
+//
+// The server subcommand writes cmd/<name>/main.go under the root of the
+// module, the main func of a command serving the handlers the
+// RegisterHandlers of the package registers, generated by handler with
+// routes, and the health handlers of its RegisterHealthHandlers, if any,
+// with an http.Server: -addr, :8080 by default, along timeouts of
+// reading the requests and writing the responses, shutting down gracefully
+// on SIGINT and SIGTERM. -name names the command, the package by default.
+//
+// For example:
+//
+//  //go:generate generators handler -func PutJob -encoding encoding/json
+package main // import "github.com/azr/generators/cmd/generators"
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/azr/generators/cli"
+)
+
+// tools are the subcommands, by name.
+var tools = map[string]func(name string, args []string) error{
+	"handler":    cli.Handler,
+	"varhandler": cli.VarHandler,
+	"server":     cli.Server,
+}
+
+// Usage is a replacement usage function for the flags package.
+func Usage() {
+	fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "\tgenerators handler [flags] -func F -encoding 'encoding/json' [directory]\n")
+	fmt.Fprintf(os.Stderr, "\tgenerators handler consumer|command|job|health [flags] -func F -encoding 'encoding/json' [directory]\n")
+	fmt.Fprintf(os.Stderr, "\tgenerators handler crud [flags] -type T -encoding 'encoding/json' [directory]\n")
+	fmt.Fprintf(os.Stderr, "\tgenerators varhandler [flags] -func F [directory]\n")
+	fmt.Fprintf(os.Stderr, "\tgenerators server [flags] [directory]\n")
+	fmt.Fprintf(os.Stderr, "Run 'generators <tool> -h' for the flags of a tool.\n")
+	fmt.Fprintf(os.Stderr, "For more information, see:\n")
+	fmt.Fprintf(os.Stderr, "\thttp://godoc.org/github.com/azr/generators/cmd/generators\n")
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("generators: ")
+	if len(os.Args) < 2 {
+		Usage()
+		os.Exit(2)
+	}
+	tool, ok := tools[os.Args[1]]
+	if !ok {
+		Usage()
+		os.Exit(2)
+	}
+	log.SetPrefix("generators " + os.Args[1] + ": ")
+	err := tool("generators "+os.Args[1], os.Args[2:])
+	if err == cli.ErrUsage {
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,200 @@
+package handlergen
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"regexp"
+	"sort"
+	"strconv"
+	"text/template"
+)
+
+// Adapter tells how the generated code decodes and encodes values with an
+// encoding pkg whose API differs from the one of encoding/json. Decode and
+// Encode are go templates of expressions of type error, executed with the
+// Pkg name, the io.Reader R or the io.Writer W and the value V, a pointer to
+// decode into, like:
+//
+//	decode: '{{.Pkg}}.NewDecoder({{.R}}, &{{.Pkg}}.JsonHandle{}).Decode({{.V}})'
+//
+// An empty one is the one of encoding/json. EncodeSlice, if set, replaces
+// Encode for the slice responses, like to wrap them in a root element.
+// ContentType, if set, is the one of the responses the http handlers encode.
+type Adapter struct {
+	Decode      string `yaml:"decode"`
+	Encode      string `yaml:"encode"`
+	EncodeSlice string `yaml:"encode-slice"`
+	ContentType string `yaml:"content-type"`
+
+	decodes, encodes string // methods the values decoded and encoded must have, if any
+}
+
+// defaultAdapter is the one of the encoding pkgs with the API of
+// encoding/json.
+var defaultAdapter = Adapter{
+	Decode: "{{.Pkg}}.NewDecoder({{.R}}).Decode({{.V}})",
+	Encode: "{{.Pkg}}.NewEncoder({{.W}}).Encode({{.V}})",
+}
+
+// adapters are the built-in adapters, by import path.
+var adapters = map[string]Adapter{
+	"encoding/gob": {ContentType: "application/x-gob"},
+	"encoding/xml": {ContentType: "text/xml; charset=utf-8"},
+	// Encoders buffer the documents until closed.
+	"gopkg.in/yaml.v2": {Encode: closingEncode},
+	"gopkg.in/yaml.v3": {Encode: closingEncode},
+	// Decode also returns the MetaData of the document.
+	"github.com/BurntSushi/toml": {Decode: "func() error { _, err := {{.Pkg}}.NewDecoder({{.R}}).Decode({{.V}}); return err }()"},
+	// Decoders and encoders take a Handle, of the format.
+	"github.com/ugorji/go/codec": {
+		Decode: "{{.Pkg}}.NewDecoder({{.R}}, &{{.Pkg}}.JsonHandle{}).Decode({{.V}})",
+		Encode: "{{.Pkg}}.NewEncoder({{.W}}, &{{.Pkg}}.JsonHandle{}).Encode({{.V}})",
+	},
+	"github.com/vmihailenco/msgpack":    {ContentType: "application/msgpack"},
+	"github.com/vmihailenco/msgpack/v4": {ContentType: "application/msgpack"},
+	"github.com/vmihailenco/msgpack/v5": {ContentType: "application/msgpack"},
+	"github.com/fxamacker/cbor":         {ContentType: "application/cbor"},
+	"github.com/fxamacker/cbor/v2":      {ContentType: "application/cbor"},
+	// The types have a codec generated by msgp, like (*Job).DecodeMsg.
+	"github.com/tinylib/msgp/msgp": {
+		Decode:      "{{.Pkg}}.Decode({{.R}}, {{.V}})",
+		Encode:      "{{.Pkg}}.Encode({{.W}}, {{.V}})",
+		ContentType: "application/msgpack",
+		decodes:     "DecodeMsg",
+		encodes:     "EncodeMsg",
+	},
+}
+
+// closingEncode encodes with an encoder to close after encoding.
+const closingEncode = "func() error { e := {{.Pkg}}.NewEncoder({{.W}}); if err := e.Encode({{.V}}); err != nil { return err }; return e.Close() }()"
+
+// adapter returns the adapter of the encoding pkg at path: the one of the
+// Generator Adapters, or else the built-in one, or else the default one.
+func (g *Generator) adapter(path string) Adapter {
+	a, ok := g.Adapters[path]
+	if !ok {
+		a = adapters[path]
+		if path == "encoding/xml" {
+			a = g.xmlAdapter(a)
+		}
+	}
+	if a.Decode == "" {
+		a.Decode = defaultAdapter.Decode
+	}
+	if a.Encode == "" {
+		a.Encode = defaultAdapter.Encode
+	}
+	return a
+}
+
+// xmlAdapter returns the a adapter of encoding/xml with the XMLHeader,
+// XMLRoot and Charset options: to transcode them, the decoders read the
+// charset of the prolog of the documents, which they check.
+func (g *Generator) xmlAdapter(a Adapter) Adapter {
+	if g.Charset == "transcode" && g.Mode == "" {
+		a.Decode = "func() error { d := {{.Pkg}}.NewDecoder({{.R}}); " +
+			"d.CharsetReader = func(label string, r io.Reader) (io.Reader, error) { " +
+			"enc, err := htmlindex.Get(label); if err != nil { return nil, err }; " +
+			"return enc.NewDecoder().Reader(r), nil }; " +
+			"return d.Decode({{.V}}) }()"
+	}
+	header := ""
+	if g.XMLHeader {
+		header = "if _, err := io.WriteString({{.W}}, {{.Pkg}}.Header); err != nil { return err }; "
+		a.Encode = "func() error { " + header + "return {{.Pkg}}.NewEncoder({{.W}}).Encode({{.V}}) }()"
+	}
+	if g.XMLRoot != "" {
+		a.EncodeSlice = "func() error { " + header +
+			"e := {{.Pkg}}.NewEncoder({{.W}}); " +
+			"start := {{.Pkg}}.StartElement{Name: {{.Pkg}}.Name{Local: " + strconv.Quote(g.XMLRoot) + "}}; " +
+			"if err := e.EncodeToken(start); err != nil { return err }; " +
+			"if err := e.Encode({{.V}}); err != nil { return err }; " +
+			"if err := e.EncodeToken(start.End()); err != nil { return err }; " +
+			"return e.Flush() }()"
+	}
+	return a
+}
+
+// adapterOf returns the adapter of the encoding pkg the generated code
+// refers to by pkg.
+func (g *Generator) adapterOf(pkg string) Adapter {
+	for path, n := range g.names {
+		if n.ident() == pkg {
+			return g.adapter(path)
+		}
+	}
+	return defaultAdapter
+}
+
+// transcodes reports whether the adapter of the encoding pkg the generated
+// code refers to by pkg transcodes the charset of the documents itself, like
+// the one of encoding/xml does with the Charset option.
+func (g *Generator) transcodes(pkg string) bool {
+	if _, ok := g.Adapters["encoding/xml"]; ok || g.Charset != "transcode" || g.Mode != "" {
+		return false
+	}
+	n, ok := g.names["encoding/xml"]
+	return ok && n.ident() == pkg
+}
+
+// xmlName matches the names of XML elements, without a namespace.
+var xmlName = regexp.MustCompile(`^[\pL_][\pL\pN_.-]*$`)
+
+// pkgIdent matches the identifiers of the encoding pkg an adapter uses.
+var pkgIdent = regexp.MustCompile(`{{\s*\.Pkg\s*}}\.([\pL_][\pL\pN_]*)`)
+
+// missing returns the names the adapter of the encoding uses but its pkg
+// does not declare.
+func (g *Generator) missing(encoding encodingPkg) []string {
+	a := g.adapter(encoding.path)
+	seen := make(map[string]bool)
+	var missing []string
+	for _, m := range pkgIdent.FindAllStringSubmatch(a.Decode+a.Encode+a.EncodeSlice, -1) {
+		if name := m[1]; !encoding.declared[name] && !seen[name] {
+			seen[name] = true
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// checkCodec returns an error if the adapter of the encoding pkg the
+// generated code refers to by pkg cannot decode a body of type body, if any,
+// or encode the responses of type resp, if any, like the msgp one the types
+// without the methods msgp generates.
+func (g *Generator) checkCodec(pkg string, body, resp types.Type) error {
+	a := g.adapterOf(pkg)
+	if body != nil && a.decodes != "" {
+		if _, pointer := body.(*types.Pointer); !pointer {
+			body = types.NewPointer(body) // Decoded into &x.
+		}
+		if types.NewMethodSet(body).Lookup(nil, a.decodes) == nil {
+			return fmt.Errorf("cannot decode %s with %s: it has no %s method", types.TypeString(body, g.qualifier), pkg, a.decodes)
+		}
+	}
+	if a.encodes != "" && (g.Envelope || g.JSONAPI || g.HAL) {
+		return fmt.Errorf("cannot encode the documents wrapping its responses with %s: they have no %s method", pkg, a.encodes)
+	}
+	if resp != nil && a.encodes != "" && types.NewMethodSet(resp).Lookup(nil, a.encodes) == nil {
+		return fmt.Errorf("cannot encode its %s responses with %s: they have no %s method", types.TypeString(resp, g.qualifier), pkg, a.encodes)
+	}
+	return nil
+}
+
+// adapt returns the code the adapter of the encoding pkg the generated code
+// refers to by pkg decodes or encodes with, executing its code template with
+// Pkg set.
+func (g *Generator) adapt(pkg string, code func(Adapter) string, data struct{ Pkg, R, W, V string }) (string, error) {
+	t, err := template.New("adapter").Parse(code(g.adapterOf(pkg)))
+	if err != nil {
+		return "", fmt.Errorf("invalid adapter of %s: %s", pkg, err)
+	}
+	data.Pkg = pkg
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("executing adapter of %s: %s", pkg, err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,222 @@
+package handlergen
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// jobs is a package with a func the codec tests generate an http handler
+// for, encoding the Job it returns with the encoding of its body.
+const jobs = `package jobs
+
+type Job struct {
+	ID   string
+	Done bool
+}
+
+func PutJob(j Job) (Job, int) {
+	j.Done = true
+	return j, 200
+}
+`
+
+// roundTrip is the test of the generated http handler of PutJob, posting a
+// Job encoded with Encode and decoding the response with Decode, fmt formats
+// of the writer or reader and of the value.
+const roundTrip = `package jobs
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	var body bytes.Buffer
+	if err := %[1]s; err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	%[3]s(w, httptest.NewRequest("PUT", "/jobs", &body))
+	if w.Code != 200 {
+		t.Fatalf("status %%d: %%s", w.Code, w.Body)
+	}
+	if got := w.Header().Get("Content-Type"); got != %[4]q {
+		t.Errorf("Content-Type %%q, want %%q", got, %[4]q)
+	}
+	var got Job
+	if err := %[2]s; err != nil {
+		t.Fatal(err)
+	}
+	if want := (Job{ID: "1", Done: true}); got != want {
+		t.Errorf("response %%+v, want %%+v", got, want)
+	}
+}
+`
+
+// codec is an encoding pkg the tests round-trip a Job through.
+type codec struct {
+	path        string
+	require     string // module of the pkg, if not in the standard library
+	imports     string // of the test, for Encode and Decode
+	encode      string // fmt format of the expression encoding a Job to &body
+	decode      string // fmt format of the expression decoding w.Body into &got
+	handler     string // name of the http handler of PutJob
+	contentType string // of its responses
+	src         string // declaring the methods of Job the pkg needs, if any
+	xtest       bool   // src imports the pkg
+}
+
+// testRoundTrip generates the http handler of PutJob for c and runs the
+// test of its round trip.
+func testRoundTrip(t *testing.T, c codec) {
+	files := map[string]string{"jobs.go": jobs}
+	if c.src != "" {
+		files["codec.go"] = c.src
+	}
+	test := strings.Replace(fmt.Sprintf(roundTrip,
+		fmt.Sprintf(c.encode, "&body", `Job{ID: "1"}`),
+		fmt.Sprintf(c.decode, "w.Body", "&got"),
+		c.handler, c.contentType,
+	), "import (\n", "import (\n"+c.imports+"\n", 1)
+	g := &Generator{}
+	if c.xtest {
+		g.Tests = "xtest"
+		files = xtest(files)
+		test = strings.Replace(test, "package jobs\n", "package jobs_test\n", 1)
+	}
+	var requires []string
+	if c.require != "" {
+		requires = append(requires, c.require)
+	}
+	dir := testModule(t, files, requires...)
+	if err := generate(dir, g, []string{"PutJob"}, c.path); err != nil {
+		t.Fatal(err)
+	}
+	// Written once generated, not to be type-checked along the package.
+	if err := ioutil.WriteFile(filepath.Join(dir, "roundtrip_test.go"), []byte(test), 0644); err != nil {
+		t.Fatal(err)
+	}
+	goTest(t, dir, g)
+}
+
+// xtest returns files moved to the external test package jobs_test, whose
+// imports the loader type-checks from source rather than from export data,
+// which the pkgs of the modules have none of.
+func xtest(files map[string]string) map[string]string {
+	moved := map[string]string{"doc.go": "package jobs\n"}
+	for name, src := range files {
+		name = strings.TrimSuffix(strings.TrimSuffix(name, ".go"), "_test") + "_test.go"
+		moved[name] = strings.Replace(src, "package jobs\n", "package jobs_test\n", 1)
+	}
+	return moved
+}
+
+// msgpCodec declares the methods msgp generates for Job, as msgp would.
+const msgpCodec = `package jobs
+
+import "github.com/tinylib/msgp/msgp"
+
+func (j *Job) DecodeMsg(r *msgp.Reader) (err error) {
+	if j.ID, err = r.ReadString(); err != nil {
+		return err
+	}
+	j.Done, err = r.ReadBool()
+	return err
+}
+
+func (j Job) EncodeMsg(w *msgp.Writer) error {
+	if err := w.WriteString(j.ID); err != nil {
+		return err
+	}
+	if err := w.WriteBool(j.Done); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+`
+
+func TestMsgpackRoundTrip(t *testing.T) {
+	for _, c := range []codec{{
+		path:        "github.com/vmihailenco/msgpack",
+		require:     "github.com/vmihailenco/msgpack v4.0.4+incompatible",
+		imports:     `"github.com/vmihailenco/msgpack"`,
+		encode:      "msgpack.NewEncoder(%s).Encode(%s)",
+		decode:      "msgpack.NewDecoder(%s).Decode(%s)",
+		handler:     "PutJobHandlerMSGPACK",
+		contentType: "application/msgpack",
+	}, {
+		path:        "github.com/vmihailenco/msgpack/v4",
+		require:     "github.com/vmihailenco/msgpack/v4 v4.3.13",
+		imports:     `"github.com/vmihailenco/msgpack/v4"`,
+		encode:      "msgpack.NewEncoder(%s).Encode(%s)",
+		decode:      "msgpack.NewDecoder(%s).Decode(%s)",
+		handler:     "PutJobHandlerMSGPACK",
+		contentType: "application/msgpack",
+	}, {
+		path:        "github.com/vmihailenco/msgpack/v5",
+		require:     "github.com/vmihailenco/msgpack/v5 v5.4.1",
+		imports:     `"github.com/vmihailenco/msgpack/v5"`,
+		encode:      "msgpack.NewEncoder(%s).Encode(%s)",
+		decode:      "msgpack.NewDecoder(%s).Decode(%s)",
+		handler:     "PutJobHandlerMSGPACK",
+		contentType: "application/msgpack",
+	}, {
+		path:        "github.com/tinylib/msgp/msgp",
+		require:     "github.com/tinylib/msgp v1.6.4",
+		imports:     `"github.com/tinylib/msgp/msgp"`,
+		encode:      "msgp.Encode(%s, %s)",
+		decode:      "msgp.Decode(%s, %s)",
+		handler:     "PutJobHandlerMSGP",
+		contentType: "application/msgpack",
+		src:         msgpCodec,
+		xtest:       true,
+	}} {
+		t.Run(c.path, func(t *testing.T) { testRoundTrip(t, c) })
+	}
+}
+
+func TestMsgpWithoutCodec(t *testing.T) {
+	dir := testModule(t, xtest(map[string]string{
+		"jobs.go": jobs + `
+type Query struct{ ID string }
+
+func GetJob(q Query) (Job, int) { return Job{ID: q.ID}, 200 }
+
+func Describe(j Job) (interface{}, int) { return j, 200 }
+
+func ListJobs(j Job) ([]Job, int) { return []Job{j}, 200 }
+`,
+		"codec.go": msgpCodec,
+	}), "github.com/tinylib/msgp v1.6.4")
+	for _, test := range []struct {
+		fn, err string
+	}{
+		{"GetJob", "cannot decode *Query with msgp: it has no DecodeMsg method"},
+		{"Describe", "cannot encode its interface{} responses with msgp: they have no EncodeMsg method"},
+		{"ListJobs", "cannot encode its []Job responses with msgp: they have no EncodeMsg method"},
+	} {
+		err := generate(dir, &Generator{Tests: "xtest"}, []string{test.fn}, "github.com/tinylib/msgp/msgp")
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("generating for %s returns %v, want %s", test.fn, err, test.err)
+		}
+	}
+	// Streamed, the elements are encoded one by one.
+	if err := generate(dir, &Generator{Tests: "xtest", Stream: "ndjson"}, []string{"ListJobs"}, "github.com/tinylib/msgp/msgp"); err != nil {
+		t.Errorf("generating for ListJobs streamed: %s", err)
+	}
+}
+
+func TestGobRoundTrip(t *testing.T) {
+	testRoundTrip(t, codec{
+		path:        "encoding/gob",
+		imports:     `"encoding/gob"`,
+		encode:      "gob.NewEncoder(%s).Encode(%s)",
+		decode:      "gob.NewDecoder(%s).Decode(%s)",
+		handler:     "PutJobHandlerGOB",
+		contentType: "application/x-gob",
+	})
+}
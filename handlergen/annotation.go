@@ -0,0 +1,162 @@
+package handlergen
+
+import (
+	"fmt"
+	"go/ast"
+	"strconv"
+	"strings"
+)
+
+// Annotation is the comment marking the funcs to generate for, directly
+// above them:
+//
+//	//handler:generate encoding=encoding/json route="PUT /jobs"
+//	func PutJob(j Job) (int, interface{})
+//
+// It takes the options of a Func as key=value pairs: encoding (a
+// comma-separated list), route, websocket, stream, source, content-type,
+// content-disposition, rate-limit, rate-limit-key, breaker, idempotency,
+// api-key, public and csrf, type-args instantiating a generic func, and
+// params binding its parameters, like params=id=path,trace=header:X-Trace-ID.
+// Values holding spaces are quoted.
+const Annotation = "//handler:generate"
+
+// Annotated returns the funcs of the parsed package, or methods of the
+// Receiver or Interface, having an Annotation.
+func (g *Generator) Annotated() ([]Func, error) {
+	if g.pkg == nil {
+		return nil, fmt.Errorf("no package parsed")
+	}
+	var funcs []Func
+	annotated := func(name string, doc *ast.CommentGroup) error {
+		if doc == nil {
+			return nil
+		}
+		for _, comment := range doc.List {
+			if comment.Text != Annotation && !strings.HasPrefix(comment.Text, Annotation+" ") {
+				continue
+			}
+			fn, err := parseAnnotation(name, comment.Text[len(Annotation):])
+			if err != nil {
+				return fmt.Errorf("%s: %s", g.pkg.Fset.Position(comment.Pos()), err)
+			}
+			funcs = append(funcs, fn)
+		}
+		return nil
+	}
+	if g.Interface != "" {
+		if t := g.interfaceType(); t != nil {
+			for _, field := range t.Methods.List {
+				if len(field.Names) != 1 {
+					continue
+				}
+				if err := annotated(field.Names[0].Name, field.Doc); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return funcs, nil
+	}
+	for _, file := range g.pkg.Files {
+		for _, decl := range file.Decls {
+			decl, ok := decl.(*ast.FuncDecl)
+			if !ok || recvName(decl) != g.Receiver {
+				continue
+			}
+			if err := annotated(decl.Name.Name, decl.Doc); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return funcs, nil
+}
+
+// parseAnnotation parses the options of the name func.
+func parseAnnotation(name, options string) (Func, error) {
+	fn := Func{Name: name}
+	fields, err := splitOptions(options)
+	if err != nil {
+		return fn, err
+	}
+	for _, field := range fields {
+		kv := strings.SplitN(field, "=", 2)
+		key, value := kv[0], ""
+		if len(kv) == 2 {
+			value = kv[1]
+		}
+		if strings.HasPrefix(value, `"`) {
+			value, err = strconv.Unquote(value)
+			if err != nil {
+				return fn, fmt.Errorf("%s: invalid quoted value: %s", key, kv[1])
+			}
+		}
+		switch key {
+		case "encoding", "encodings":
+			fn.Encodings = strings.Split(value, ",")
+		case "type-args":
+			fn.TypeArgs = splitTypeList(value)
+		case "route":
+			fn.Route = value
+		case "websocket":
+			fn.WebSocket = value == "" || value == "true"
+		case "stream":
+			fn.Stream = value
+		case "source":
+			fn.Source = value
+		case "params":
+			fn.Params = make(map[string]string)
+			for _, param := range strings.Split(value, ",") {
+				kv := strings.SplitN(param, "=", 2)
+				if len(kv) != 2 || kv[0] == "" {
+					return fn, fmt.Errorf("params: want name=source, got %s", param)
+				}
+				fn.Params[kv[0]] = kv[1]
+			}
+		case "content-type":
+			fn.ContentType = value
+		case "content-disposition":
+			fn.ContentDisposition = value
+		case "rate-limit":
+			fn.RateLimit = value
+		case "rate-limit-key":
+			fn.RateLimitKey = value
+		case "breaker":
+			fn.Breaker = value
+		case "idempotency":
+			fn.Idempotency = value == "" || value == "true"
+		case "api-key":
+			fn.APIKey = value
+		case "public":
+			fn.Public = value == "" || value == "true"
+		case "csrf":
+			fn.CSRF = value == "" || value == "true"
+		default:
+			return fn, fmt.Errorf("unknown option: %s", key)
+		}
+	}
+	return fn, nil
+}
+
+// splitOptions splits options around spaces that are not quoted.
+func splitOptions(options string) ([]string, error) {
+	var fields []string
+	for s := strings.TrimSpace(options); s != ""; s = strings.TrimSpace(s) {
+		i := 0
+		for ; i < len(s) && s[i] != ' ' && s[i] != '\t'; i++ {
+			if s[i] != '"' {
+				continue
+			}
+			for i++; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' {
+					i++
+				}
+			}
+			if i >= len(s) {
+				return nil, fmt.Errorf("unterminated quoted value: %s", s)
+			}
+		}
+		fields = append(fields, s[:i])
+		s = s[i:]
+	}
+	return fields, nil
+}
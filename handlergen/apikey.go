@@ -0,0 +1,69 @@
+package handlergen
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// APIKey is the data the apikey templates are executed with, with the
+// APIKey option.
+type APIKey struct {
+	Validator string // interface validating the API keys
+	Var       string // package var of the Validator the http handlers use
+	Handler   string // name of the handler checking the API key of its requests, if any
+	Key       string // expression of the API key of the request in the handler, like r.Header.Get("X-API-Key")
+}
+
+// apiKeySource returns the expression reading the API key of a request from
+// where spec tells: header, X-API-Key by default, or query, api_key by
+// default, optionally followed by the name, like header:Authorization.
+func apiKeySource(spec string) (string, error) {
+	kind, name := spec, ""
+	if i := strings.Index(spec, ":"); i >= 0 {
+		kind, name = spec[:i], spec[i+1:]
+	}
+	switch kind {
+	case "header":
+		if name == "" {
+			name = "X-API-Key"
+		}
+		return "r.Header.Get(" + strconv.Quote(name) + ")", nil
+	case "query":
+		if name == "" {
+			name = "api_key"
+		}
+		return "r.URL.Query().Get(" + strconv.Quote(name) + ")", nil
+	}
+	return "", fmt.Errorf("unknown API key source %s, want header or query, optionally followed by :<name>", spec)
+}
+
+// apiKeyNames returns the APIKey the declarations are executed with.
+func (g *Generator) apiKeyNames() APIKey {
+	return APIKey{
+		Validator: g.exported("APIKeyValidator"),
+		Var:       g.exported("HandlerAPIKeys"),
+	}
+}
+
+// checkAPIKey returns the code of the h http handler responding 401
+// Unauthorized or 403 Forbidden to the requests whose API key the Validator
+// does not let call it, injected at its top; none if the func has none.
+func (g *Generator) checkAPIKey(h Handler, fn Func, funcName string) string {
+	spec := fn.APIKey
+	if spec == "" {
+		spec = g.APIKey
+	}
+	if spec == "" || spec == "none" {
+		return ""
+	}
+	key, err := apiKeySource(spec)
+	if err != nil {
+		g.errorf("%s: %s", funcName, err)
+		return ""
+	}
+	g.apiKeys = true
+	n := g.apiKeyNames()
+	n.Handler, n.Key = h.Name, key
+	return g.code("apikey", n)
+}
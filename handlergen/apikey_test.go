@@ -0,0 +1,91 @@
+package handlergen
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// apiKeyTest is the test of the http handlers of PutJob and FindJob,
+// responding 401 to the requests whose API key is missing or invalid, or all
+// until HandlerAPIKeys is set, and 403 to the ones it does not allow, and of
+// Health, whose API key is lifted.
+const apiKeyTest = `package jobs
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// keys maps the API keys to the handler they are allowed to call.
+type keys map[string]string
+
+func (k keys) ValidateAPIKey(ctx context.Context, key, handler string) (valid, allowed bool, err error) {
+	allowedHandler, valid := k[key]
+	return valid, allowedHandler == handler, nil
+}
+
+func TestAPIKey(t *testing.T) {
+	for _, test := range []struct {
+		validator APIKeyValidator
+		handler   http.HandlerFunc
+		target    string
+		key       string
+		status    int
+	}{
+		{nil, PutJobHandlerJSON, "/jobs", "writer", 401},
+		{keys{"writer": "PutJobHandlerJSON"}, PutJobHandlerJSON, "/jobs", "", 401},
+		{keys{"writer": "PutJobHandlerJSON"}, PutJobHandlerJSON, "/jobs", "unknown", 401},
+		{keys{"reader": "FindJobHandlerJSON"}, PutJobHandlerJSON, "/jobs", "reader", 403},
+		{keys{"writer": "PutJobHandlerJSON"}, PutJobHandlerJSON, "/jobs", "writer", 200},
+		{keys{"reader": "FindJobHandlerJSON"}, FindJobHandlerJSON, "/jobs", "reader", 401}, // read from the query
+		{keys{"reader": "FindJobHandlerJSON"}, FindJobHandlerJSON, "/jobs?key=reader", "", 200},
+		{nil, HealthHandlerJSON, "/health", "", 200},
+	} {
+		HandlerAPIKeys = test.validator
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", test.target, strings.NewReader("{\"ID\": \"1\"}"))
+		if test.key != "" {
+			r.Header.Set("X-API-Key", test.key)
+		}
+		test.handler(w, r)
+		if w.Code != test.status {
+			t.Errorf("%s with key %q: responded %d, want %d", test.target, test.key, w.Code, test.status)
+		}
+	}
+}
+`
+
+func TestAPIKey(t *testing.T) {
+	dir := testModule(t, map[string]string{"jobs.go": `package jobs
+
+type Job struct{ ID string }
+
+func PutJob(j Job) (Job, int) {
+	return j, 200
+}
+
+func FindJob(j Job) (Job, int) {
+	return j, 200
+}
+
+func Health(j Job) (Job, int) {
+	return j, 200
+}
+`})
+	g := &Generator{APIKey: "header"}
+	if err := g.Add(Func{Name: "FindJob", APIKey: "query:key"}, Func{Name: "Health", APIKey: "none"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := generate(dir, g, []string{"PutJob"}, "encoding/json"); err != nil {
+		t.Fatal(err)
+	}
+	// Written once generated, not to be type-checked along the package.
+	if err := ioutil.WriteFile(filepath.Join(dir, "apikey_test.go"), []byte(apiKeyTest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	goTest(t, dir, g)
+}
@@ -0,0 +1,33 @@
+package handlergen
+
+// Auth is the data the auth templates are executed with, with the Auth
+// option.
+type Auth struct {
+	Authenticator string // interface authenticating the requests
+	Var           string // package var of the Authenticator the http handlers use
+	Principal     string // type of who a request is authenticated as
+	Get           string // func returning the Principal of the request of a context
+	Declare       bool   // the Principal type is declared along, unless the parsed package declares it
+}
+
+// authNames returns the Auth the declarations are executed with.
+func (g *Generator) authNames() Auth {
+	a := Auth{
+		Authenticator: g.exported("Authenticator"),
+		Var:           g.exported("HandlerAuthenticator"),
+		Principal:     g.exported("Principal"),
+		Get:           g.exported("RequestPrincipal"),
+	}
+	obj := g.pkg.Types.Scope().Lookup(a.Principal)
+	a.Declare = g.Package != "" || obj == nil || g.generated(obj.Pos())
+	return a
+}
+
+// authenticate returns the code of the http handlers responding 401
+// Unauthorized to the requests the Authenticator fails, injected at their
+// top, or else putting the Principal in the context of the request.
+func (g *Generator) authenticate() string {
+	g.authed = true
+	g.addImport("context")
+	return g.code("auth", nil)
+}
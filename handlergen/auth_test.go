@@ -0,0 +1,88 @@
+package handlergen
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// authTest is the test of the http handlers of PutJob, responding 401 to the
+// requests HandlerAuthenticator fails, or all until it is set, and of Health,
+// public.
+const authTest = `package jobs
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type tokens map[string]string
+
+func (t tokens) Authenticate(r *http.Request) (Principal, error) {
+	user, ok := t[r.Header.Get("Authorization")]
+	if !ok {
+		return nil, errors.New("unknown token")
+	}
+	return user, nil
+}
+
+func TestAuth(t *testing.T) {
+	for _, test := range []struct {
+		authenticator Authenticator
+		auth          string
+		status        int
+	}{
+		{nil, "Bearer ann", 401},
+		{tokens{"Bearer ann": "ann"}, "", 401},
+		{tokens{"Bearer ann": "ann"}, "Bearer bob", 401},
+		{tokens{"Bearer ann": "ann"}, "Bearer ann", 200},
+	} {
+		HandlerAuthenticator = test.authenticator
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/jobs", strings.NewReader("{\"ID\": \"1\"}"))
+		r.Header.Set("Authorization", test.auth)
+		PutJobHandlerJSON(w, r)
+		if w.Code != test.status {
+			t.Errorf("authorization %q: responded %d, want %d", test.auth, w.Code, test.status)
+		}
+	}
+	HandlerAuthenticator = nil
+	w := httptest.NewRecorder()
+	HealthHandlerJSON(w, httptest.NewRequest("POST", "/health", strings.NewReader("{}")))
+	if w.Code != 200 {
+		t.Errorf("public Health responded %d, want 200", w.Code)
+	}
+}
+`
+
+func TestAuth(t *testing.T) {
+	dir := testModule(t, map[string]string{"jobs.go": `package jobs
+
+type Job struct{ ID string }
+
+func PutJob(j Job) (Job, int) {
+	return j, 200
+}
+
+type Status struct{ OK bool }
+
+func Health(s Status) (Status, int) {
+	return Status{OK: true}, 200
+}
+`})
+	g := &Generator{Auth: true}
+	if err := g.Add(Func{Name: "Health", Public: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := generate(dir, g, []string{"PutJob"}, "encoding/json"); err != nil {
+		t.Fatal(err)
+	}
+	// Written once generated, not to be type-checked along the package.
+	if err := ioutil.WriteFile(filepath.Join(dir, "auth_test.go"), []byte(authTest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	goTest(t, dir, g)
+}
@@ -0,0 +1,195 @@
+package handlergen
+
+import (
+	"fmt"
+	"go/types"
+	"sort"
+	"strings"
+)
+
+// Param is a parameter of F read from the request instead of decoded from
+// the body, for http handlers.
+type Param struct {
+	Var    string // name of the variable holding it, like param1
+	Source string // expression reading it as a string, like r.URL.Query().Get("id")
+	Parse  string // call parsing Source into (parsed, err), if needed
+}
+
+// basic reports whether a parameter of type t can be read from a string,
+// like an int or a string.
+func basic(t types.Type) bool {
+	u, ok := t.Underlying().(*types.Basic)
+	return ok && u.Info()&types.IsComplex == 0
+}
+
+// hasWildcard reports whether the route pattern, like "GET /jobs/{id}", has
+// a {name} or {name...} wildcard.
+func hasWildcard(pattern, name string) bool {
+	for _, segment := range strings.Split(pattern, "/") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") &&
+			strings.TrimSuffix(segment[1:len(segment)-1], "...") == name {
+			return true
+		}
+	}
+	return false
+}
+
+// bind sets how h gets the parameters of fn: at most one is decoded from the
+// body into x, the others are read from the query, path or headers, as the
+// fn Params tell. Parameters of a basic type default to source, or to the
+// query when there are many, the structs with filter tags or embedding a
+// cursor are read from the query, and the Page is too with Pagination. The
+// cursor is read from the cursor query param. With JWT, the claims are read
+// from the bearer token, before the others, and with Sessions, the *Session
+// is loaded from the session cookie.
+func (g *Generator) bind(h *Handler, fn Func, source string, sig signature) {
+	var names []string
+	for name := range fn.Params {
+		names = append(names, name)
+	}
+	sort.Strings(names) // Report the same error first.
+	for _, name := range names {
+		found := false
+		for _, p := range sig.params {
+			found = found || p.name == name
+		}
+		if !found {
+			g.errorf("%s has no parameter %s to bind", fn.Name, name)
+			return
+		}
+	}
+	h.T, h.Pointer = "", false
+	g.request = request{}
+	var args []string
+	for i, p := range sig.params {
+		spec, bound := fn.Params[p.name]
+		if !bound && g.Pagination && g.isPage(p.t) {
+			h.Page = fmt.Sprintf("param%d", i)
+			h.Params = append(h.Params, Param{Var: h.Page, Parse: g.pagination().Parse + "(r)"})
+			for _, key := range []string{"limit", "offset"} {
+				g.request.params = append(g.request.params, requestParam{in: "query", key: key, t: types.Typ[types.Int], optional: true})
+			}
+			args = append(args, h.Page)
+			continue
+		}
+		if !bound && g.Sessions && g.isSession(p.t) {
+			if h.Session != nil {
+				g.errorf("%s: only one parameter can be the session", fn.Name)
+				return
+			}
+			v := fmt.Sprintf("param%d", i)
+			h.Session = &Session{Var: v, None: g.sessionNames().None, Load: "loadSession", Save: "saveSession"}
+			args = append(args, v)
+			continue
+		}
+		if !bound && g.JWT && isClaims(p.t) {
+			parse, ok := g.claims(fn.Name, p.t)
+			if !ok {
+				return
+			}
+			v := fmt.Sprintf("param%d", i)
+			h.Claims = append(h.Claims, Param{Var: v, Parse: parse + "(r)"})
+			if _, pointer := p.t.(*types.Pointer); pointer {
+				v = "&" + v
+			}
+			args = append(args, v)
+			continue
+		}
+		if !bound && isFilter(p.t) {
+			parse, ok := g.filter(fn.Name, p.t)
+			if !ok {
+				return
+			}
+			v := fmt.Sprintf("param%d", i)
+			h.Params = append(h.Params, Param{Var: v, Parse: parse + "(r)"})
+			for _, f := range g.filters {
+				if f.Name != parse {
+					continue
+				}
+				for _, field := range f.Fields {
+					g.request.params = append(g.request.params, requestParam{in: "query", key: field.Key, optional: true})
+				}
+			}
+			g.bindCursor(h, p.t, v)
+			if _, pointer := p.t.(*types.Pointer); pointer {
+				v = "&" + v
+			}
+			args = append(args, v)
+			continue
+		}
+		if _, cursor := cursorField(p.t); !bound && cursor != nil {
+			v := fmt.Sprintf("param%d", i)
+			h.Params = append(h.Params, Param{Var: v, Source: g.qualify(strings.TrimPrefix(p.fullname, "*")) + "{}"})
+			g.bindCursor(h, p.t, v)
+			if _, pointer := p.t.(*types.Pointer); pointer {
+				v = "&" + v
+			}
+			args = append(args, v)
+			continue
+		}
+		if !bound && basic(p.t) && (len(sig.params) > 1 || source != "" && source != "body") {
+			spec, bound = source, true
+			if spec == "" || spec == "body" {
+				spec = "query"
+			}
+		}
+		if !bound || spec == "body" {
+			if h.T != "" {
+				g.errorf("%s: only one parameter can be decoded from the body, bind the others to the query, a path value or a header", fn.Name)
+				return
+			}
+			h.Pointer = strings.HasPrefix(p.fullname, "*")
+			h.T = g.qualify(strings.TrimPrefix(p.fullname, "*"))
+			g.request.body = p.t
+			g.bindCursor(h, p.t, "x")
+			args = append(args, "x")
+			continue
+		}
+
+		kv := strings.SplitN(spec, ":", 2)
+		kind, key := kv[0], p.name
+		if len(kv) == 2 {
+			key = kv[1]
+		}
+		if key == "" {
+			g.errorf("%s: parameter %d needs a name to be read from the %s", fn.Name, i, kind)
+			return
+		}
+		var read string
+		switch kind {
+		case "query":
+			read = fmt.Sprintf("r.URL.Query().Get(%q)", key)
+		case "path":
+			if fn.Route == "" {
+				g.errorf("%s: parameter %s is read from a path value, but the func has no route holding it, like \"GET /jobs/{%s}\"", fn.Name, p.name, key)
+				return
+			}
+			if !hasWildcard(fn.Route, key) {
+				g.errorf("%s: route %q has no {%s} wildcard to read parameter %s from", fn.Name, fn.Route, key, p.name)
+				return
+			}
+			read = fmt.Sprintf("r.PathValue(%q)", key)
+		case "header":
+			read = fmt.Sprintf("r.Header.Get(%q)", key)
+		default:
+			g.errorf("%s: unknown source of %s: %s", fn.Name, key, kind)
+			return
+		}
+		f, ok := g.envField(p.t, read)
+		if !ok || f.Split {
+			g.errorf("%s: cannot read a %s from the %s", fn.Name, p.fullname, kind)
+			return
+		}
+		v := fmt.Sprintf("param%d", i)
+		h.Params = append(h.Params, Param{Var: v, Source: read, Parse: f.Parse})
+		g.request.params = append(g.request.params, requestParam{in: kind, key: key, t: p.t})
+		if f.Type != "" {
+			v = f.Type + "(" + v + ")"
+		}
+		args = append(args, v)
+	}
+	if h.Cursor != nil {
+		g.request.params = append(g.request.params, requestParam{in: "query", key: "cursor", optional: true})
+	}
+	h.Args = strings.Join(args, ", ")
+}
@@ -0,0 +1,40 @@
+package handlergen
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPathParamWithoutWildcard(t *testing.T) {
+	dir := testModule(t, map[string]string{"jobs.go": `package jobs
+
+type Job struct{ ID string }
+
+func GetJob(id string) (Job, int) { return Job{ID: id}, 200 }
+
+func PutJob(id string, j Job) (Job, int) { return j, 200 }
+`})
+	for _, test := range []struct {
+		fn  Func
+		g   *Generator
+		err string
+	}{
+		{Func{Name: "GetJob", Route: "GET /jobs/{id}"}, &Generator{Source: "path"}, ""},
+		{Func{Name: "GetJob", Route: "GET /jobs/{id...}"}, &Generator{Source: "path"}, ""},
+		{Func{Name: "GetJob", Route: "GET /jobs/{name}"}, &Generator{Source: "path"}, `jobs.go:5:6: GetJob: route "GET /jobs/{name}" has no {id} wildcard`},
+		{Func{Name: "GetJob"}, &Generator{Source: "path"}, "jobs.go:5:6: GetJob: parameter id is read from a path value, but the func has no route"},
+		{Func{Name: "PutJob", Route: "PUT /jobs/{id}", Params: map[string]string{"id": "path:job"}}, &Generator{}, `jobs.go:7:6: PutJob: route "PUT /jobs/{id}" has no {job} wildcard`},
+	} {
+		test.g.Add(test.fn)
+		if err := test.g.AddEncoding("encoding/json"); err != nil {
+			t.Fatal(err)
+		}
+		err := test.g.Parse(dir)
+		if err == nil {
+			err = test.g.Render(&strings.Builder{})
+		}
+		if test.err == "" && err != nil || test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+			t.Errorf("generating %s for %s returns %v, want %q", test.fn.Name, test.fn.Route, err, test.err)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package handlergen
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Breaker is the data the breaker templates are executed with: the circuit
+// breaker of a func, shared by its http handlers, like with the Breaker
+// option.
+type Breaker struct {
+	Func     string // name of the func the breaker stops calling
+	Var      string // package var of the breaker, like breakerPutJob
+	Failures int    // 5xx statuses in a row opening it
+	Timeout  string // expression of the time it stays open, like 30 * time.Second
+	Open     string // code of the handler responding 503 Service Unavailable while it is open, before the call
+}
+
+// parseBreaker parses the thresholds of a circuit breaker: the failures in
+// a row opening it, optionally followed by the time it stays open, like 5
+// or 5/30s; a minute otherwise.
+func parseBreaker(s string) (failures int, timeout time.Duration, err error) {
+	spec, d := s, "1m"
+	if i := strings.Index(s, "/"); i >= 0 {
+		spec, d = s[:i], s[i+1:]
+	}
+	failures, err = strconv.Atoi(spec)
+	if err != nil || failures < 1 {
+		return 0, 0, fmt.Errorf("invalid breaker %q, want failures/timeout like 5/30s", s)
+	}
+	timeout, err = time.ParseDuration(d)
+	if err != nil || timeout <= 0 {
+		return 0, 0, fmt.Errorf("invalid breaker %q: want a positive timeout, like 30s", s)
+	}
+	return failures, timeout, nil
+}
+
+// durationExpr returns the expression of d, like 30 * time.Second.
+func durationExpr(d time.Duration) string {
+	for _, unit := range []struct {
+		d    time.Duration
+		name string
+	}{{time.Hour, "Hour"}, {time.Minute, "Minute"}, {time.Second, "Second"}, {time.Millisecond, "Millisecond"}} {
+		if d%unit.d == 0 {
+			return strconv.FormatInt(int64(d/unit.d), 10) + " * time." + unit.name
+		}
+	}
+	return "time.Duration(" + strconv.FormatInt(int64(d), 10) + ")"
+}
+
+// breaker returns the circuit breaker of the http handlers of the func,
+// declaring it once; nil if the func has none.
+func (g *Generator) breaker(fn Func, funcName string) *Breaker {
+	spec := fn.Breaker
+	if spec == "" {
+		spec = g.Breaker
+	}
+	if spec == "" || spec == "none" {
+		return nil
+	}
+	failures, timeout, err := parseBreaker(spec)
+	if err != nil {
+		g.errorf("%s: %s", funcName, err)
+		return nil
+	}
+	b := Breaker{
+		Func:     funcName,
+		Var:      "breaker" + funcName,
+		Failures: failures,
+		Timeout:  durationExpr(timeout),
+	}
+	declared := false
+	for _, d := range g.breakers {
+		declared = declared || d.Var == b.Var
+	}
+	if !declared {
+		g.breakers = append(g.breakers, b)
+	}
+	g.addImport("errors")
+	b.Open = g.code("breaker", b)
+	return &b
+}
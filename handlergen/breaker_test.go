@@ -0,0 +1,66 @@
+package handlergen
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// breakerTest is the test of the breaker of FindJob, counting the statuses
+// its http handler responds once the error-statuses mapped, not the ones it
+// returns.
+const breakerTest = `package jobs
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBreaker(t *testing.T) {
+	for i, test := range []struct {
+		id     string
+		status int
+	}{
+		{"1", 404}, {"1", 404}, {"1", 404}, // mapped
+		{"down", 500}, {"down", 500},
+		{"1", 503}, // open
+	} {
+		w := httptest.NewRecorder()
+		FindJobHandlerJSON(w, httptest.NewRequest("POST", "/jobs", strings.NewReader("{\"ID\": \""+test.id+"\"}")))
+		if w.Code != test.status {
+			t.Errorf("request %d, of job %s, responded %d, want %d", i, test.id, w.Code, test.status)
+		}
+	}
+}
+`
+
+func TestBreakerMappedStatuses(t *testing.T) {
+	dir := testModule(t, map[string]string{"jobs.go": `package jobs
+
+import "errors"
+
+var (
+	ErrNotFound = errors.New("not found")
+	ErrDown     = errors.New("down")
+)
+
+type Job struct{ ID string }
+
+func FindJob(j Job) (interface{}, int) {
+	if j.ID == "down" {
+		return ErrDown, 500
+	}
+	return ErrNotFound, 500
+}
+`})
+	g := &Generator{Breaker: "2/1h", ErrorStatuses: map[string]int{"ErrNotFound": 404}}
+	if err := generate(dir, g, []string{"FindJob"}, "encoding/json"); err != nil {
+		t.Fatal(err)
+	}
+	// Written once generated, not to be type-checked along the package.
+	if err := ioutil.WriteFile(filepath.Join(dir, "breaker_test.go"), []byte(breakerTest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	goTest(t, dir, g)
+}
@@ -0,0 +1,63 @@
+package handlergen
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// charsetTest is the test of the http handlers of PutJob transcoding the
+// request bodies to UTF-8: from the charset of their Content-Type, or of
+// their prolog for XML.
+const charsetTest = `package jobs
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCharset(t *testing.T) {
+	for _, test := range []struct {
+		handler     http.HandlerFunc
+		contentType string
+		body        string
+		status      int
+	}{
+		{PutJobHandlerJSON, "application/json; charset=ISO-8859-1", "{\"Name\": \"caf\xe9\"}", 200},
+		{PutJobHandlerJSON, "application/json; charset=nope", "{\"Name\": \"caf\xe9\"}", 415},
+		{PutJobHandlerXML, "text/xml; charset=ISO-8859-1", "<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?><Job><Name>caf\xe9</Name></Job>", 200},
+		{PutJobHandlerXML, "text/xml", "<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?><Job><Name>caf\xe9</Name></Job>", 200},
+		{PutJobHandlerXML, "text/xml", "<?xml version=\"1.0\" encoding=\"nope\"?><Job><Name>cafe</Name></Job>", 400},
+	} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("PUT", "/jobs", strings.NewReader(test.body))
+		r.Header.Set("Content-Type", test.contentType)
+		test.handler(w, r)
+		if w.Code != test.status {
+			t.Errorf("%s %q: responded %d, want %d", test.contentType, test.body, w.Code, test.status)
+		} else if w.Code == 200 && !strings.Contains(w.Body.String(), "café") {
+			t.Errorf("%s %q: responded %s, want café", test.contentType, test.body, w.Body)
+		}
+	}
+}
+`
+
+func TestCharsetTranscode(t *testing.T) {
+	dir := testModule(t, map[string]string{"jobs.go": `package jobs
+
+type Job struct{ Name string }
+
+func PutJob(j Job) (Job, int) { return j, 200 }
+`}, "golang.org/x/text v0.14.0")
+	g := &Generator{Charset: "transcode"}
+	if err := generate(dir, g, []string{"PutJob"}, "encoding/json", "encoding/xml"); err != nil {
+		t.Fatal(err)
+	}
+	// Written once generated, not to be type-checked along the package.
+	if err := ioutil.WriteFile(filepath.Join(dir, "charset_test.go"), []byte(charsetTest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	goTest(t, dir, g)
+}
@@ -0,0 +1,63 @@
+package handlergen
+
+import (
+	"go/types"
+	"strings"
+)
+
+// Claims is the data the claims template is executed with, with the JWT
+// option: a parameter of a type named like Claims, read from the bearer
+// token of the Authorization header.
+type Claims struct {
+	Name    string   // of the func parsing it, like ParseClaims
+	T       string   // type of the claims, qualified by its pkg name if needed
+	Keyfunc string   // package var of the jwt.Keyfunc verifying the tokens
+	Methods []string // signing methods of the tokens, if not the ones of the kind of key
+	Declare bool     // the Keyfunc is declared along, before any other claims
+}
+
+// isClaims reports whether t is a type named like Claims or AdminClaims, or
+// a pointer to one.
+func isClaims(t types.Type) bool {
+	named := namedType(t)
+	return named != nil && strings.HasSuffix(named.Obj().Name(), "Claims")
+}
+
+// claims returns the name of the func parsing the claims parameter of type
+// t from the bearer token of the request, declaring it once for every
+// handler taking one.
+func (g *Generator) claims(funcName string, t types.Type) (string, bool) {
+	named := namedType(t)
+	c := Claims{
+		Name:    g.exported("Parse" + named.Obj().Name()),
+		T:       types.TypeString(named, g.qualifier),
+		Keyfunc: g.exported("JWTKeyfunc"),
+		Methods: g.JWTMethods,
+	}
+	for _, declared := range g.jwtClaims {
+		if declared.Name == c.Name && declared.T != c.T {
+			g.errorf("%s: %s parses both %s and %s", funcName, c.Name, declared.T, c.T)
+			return "", false
+		}
+		if declared.Name == c.Name {
+			return c.Name, true
+		}
+	}
+	if obj, _, _ := types.LookupFieldOrMethod(types.NewPointer(named), true, nil, "GetExpirationTime"); obj == nil {
+		g.errorf("%s: *%s is not a jwt.Claims, like a struct embedding jwt.RegisteredClaims", funcName, c.T)
+		return "", false
+	}
+	c.Declare = len(g.jwtClaims) == 0
+	g.jwtClaims = append(g.jwtClaims, c)
+	if len(c.Methods) == 0 && c.Declare {
+		g.addImport("crypto/ecdsa")
+		g.addImport("crypto/ed25519")
+		g.addImport("crypto/rsa")
+		g.addImport("fmt")
+	}
+	g.addImport("errors")
+	g.addImport("github.com/golang-jwt/jwt/v5")
+	g.addImport("strings")
+	g.execute("claims", c)
+	return c.Name, true
+}
@@ -0,0 +1,144 @@
+package handlergen
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// claimsSrc declares PutJob, taking the claims of the bearer token of its
+// requests.
+const claimsSrc = `package jobs
+
+import "github.com/golang-jwt/jwt/v5"
+
+type Claims struct {
+	jwt.RegisteredClaims
+}
+
+type Job struct{ ID, Owner string }
+
+func PutJob(c Claims, j Job) (Job, int) {
+	j.Owner = c.Subject
+	return j, 200
+}
+`
+
+// claimsTest is the test of the http handler of PutJob, responding 401 to the
+// requests whose bearer token is missing, malformed, expired, or signed with
+// a method of another kind than the key.
+const claimsTest = `package jobs
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var key = []byte("secret")
+
+func token(t *testing.T, method jwt.SigningMethod, key interface{}, expires time.Time) string {
+	t.Helper()
+	s, err := jwt.NewWithClaims(method, jwt.RegisteredClaims{Subject: "ann", ExpiresAt: jwt.NewNumericDate(expires)}).SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "Bearer " + s
+}
+
+func TestClaims(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	JWTKeyfunc = func(*jwt.Token) (interface{}, error) { return key, nil }
+	for _, test := range []struct {
+		name, auth string
+		status     int
+	}{
+		{"valid", token(t, jwt.SigningMethodHS256, key, time.Now().Add(time.Hour)), 200},
+		{"missing", "", 401},
+		{"malformed", "Bearer not.a.token", 401},
+		{"expired", token(t, jwt.SigningMethodHS256, key, time.Now().Add(-time.Hour)), 401},
+		{"wrong alg", token(t, jwt.SigningMethodRS256, rsaKey, time.Now().Add(time.Hour)), 401},
+	} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/jobs", strings.NewReader("{\"ID\": \"1\"}"))
+		if test.auth != "" {
+			r.Header.Set("Authorization", test.auth)
+		}
+		PutJobHandlerJSON(w, r)
+		if w.Code != test.status {
+			t.Errorf("%s token: responded %d, want %d", test.name, w.Code, test.status)
+		}
+		if test.status == 200 && !strings.Contains(w.Body.String(), "\"Owner\":\"ann\"") {
+			t.Errorf("%s token: responded %s, want the job of ann", test.name, w.Body)
+		}
+	}
+}
+`
+
+// claimsMethodsTest is the test of the http handler of PutJob, responding 401
+// to the requests whose bearer token is signed with a method of the kind of
+// the key but not one of JWTMethods.
+const claimsMethodsTest = `package jobs
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestClaimsMethods(t *testing.T) {
+	key := []byte("secret")
+	JWTKeyfunc = func(*jwt.Token) (interface{}, error) { return key, nil }
+	for _, test := range []struct {
+		method jwt.SigningMethod
+		status int
+	}{
+		{jwt.SigningMethodHS512, 200},
+		{jwt.SigningMethodHS256, 401},
+	} {
+		s, err := jwt.NewWithClaims(test.method, jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))}).SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/jobs", strings.NewReader("{\"ID\": \"1\"}"))
+		r.Header.Set("Authorization", "Bearer "+s)
+		PutJobHandlerJSON(w, r)
+		if w.Code != test.status {
+			t.Errorf("%s token: responded %d, want %d", test.method.Alg(), w.Code, test.status)
+		}
+	}
+}
+`
+
+func TestClaims(t *testing.T) {
+	for _, test := range []struct {
+		methods []string
+		src     string
+	}{
+		{nil, claimsTest},
+		{[]string{"HS512"}, claimsMethodsTest},
+	} {
+		dir := testModule(t, map[string]string{"jobs.go": claimsSrc}, "github.com/golang-jwt/jwt/v5 v5.2.1")
+		g := &Generator{JWT: true, JWTMethods: test.methods}
+		if err := generate(dir, g, []string{"PutJob"}, "encoding/json"); err != nil {
+			t.Fatal(err)
+		}
+		// Written once generated, not to be type-checked along the package.
+		if err := ioutil.WriteFile(filepath.Join(dir, "claims_test.go"), []byte(test.src), 0644); err != nil {
+			t.Fatal(err)
+		}
+		goTest(t, dir, g)
+	}
+}
@@ -0,0 +1,149 @@
+package handlergen
+
+import (
+	"strings"
+	"unicode"
+
+	"go/types"
+)
+
+// CommandFlag is a cobra flag bound to a field of the parameter.
+type CommandFlag struct {
+	Method string // of cobra's FlagSet, like StringVar
+	Field  string // Go name of the field
+	Name   string // of the flag
+	Type   string // basic type the field is converted to, if named
+	Zero   string // default value
+}
+
+// buildCommand generates a cobra command for a single func and encoding.
+func (g *Generator) buildCommand(funcName, pkgName, paramfullname string, pointer, statusFirst, slice bool) {
+	g.addImport("fmt")
+	g.addImport("github.com/spf13/cobra")
+
+	var flags []CommandFlag
+	if st, ok := g.lookupType(paramfullname).Underlying().(*types.Struct); ok {
+		for i := 0; i < st.NumFields(); i++ {
+			field := st.Field(i)
+			if !field.Exported() || field.Anonymous() {
+				continue
+			}
+			f, ok := commandFlag(field.Type())
+			if !ok {
+				g.warnf("%s.%s: no flag for type %s, skipped", paramfullname, field.Name(), field.Type())
+				continue
+			}
+			f.Field = field.Name()
+			f.Name = kebabCase(field.Name())
+			flags = append(flags, f)
+		}
+	} else {
+		g.warnf("%s is not a struct, command %s will have no flags", paramfullname, funcName)
+	}
+
+	g.execute("command", struct {
+		Name        string
+		Func        string
+		EncodingPkg string
+		T           string
+		Pointer     bool
+		StatusFirst bool
+		Slice       bool
+		Qual        string
+		Receiver    string
+		Use         string
+		Flags       []CommandFlag
+	}{
+		Name:        g.exported("New" + funcName + "Command" + strings.ToUpper(pkgName)),
+		Func:        funcName,
+		EncodingPkg: pkgName,
+		T:           g.qualify(paramfullname),
+		Pointer:     pointer,
+		Slice:       slice,
+		StatusFirst: statusFirst,
+		Qual:        g.qual(),
+		Receiver:    g.receiver(),
+		Use:         kebabCase(funcName),
+		Flags:       flags,
+	})
+}
+
+// lookupType finds the named type T or pkg.T from the parsed package.
+func (g *Generator) lookupType(fullname string) types.Type {
+	scope := g.pkg.Types.Scope()
+	name := fullname
+	if i := strings.Index(fullname, "."); i >= 0 {
+		for _, pkg := range g.pkg.Types.Imports() {
+			if pkg.Name() == fullname[:i] {
+				scope = pkg.Scope()
+			}
+		}
+		name = fullname[i+1:]
+	}
+	obj := scope.Lookup(name)
+	if obj == nil {
+		return types.Typ[types.Invalid]
+	}
+	return obj.Type()
+}
+
+// commandFlag tells which cobra flag can hold a t.
+func commandFlag(t types.Type) (CommandFlag, bool) {
+	if named, ok := t.(*types.Named); ok {
+		obj := named.Obj()
+		if obj.Pkg() != nil && obj.Pkg().Path() == "time" && obj.Name() == "Duration" {
+			return CommandFlag{Method: "DurationVar", Zero: "0"}, true
+		}
+	}
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		f := CommandFlag{Zero: "0"}
+		switch u.Kind() {
+		case types.String:
+			f.Method, f.Zero = "StringVar", `""`
+		case types.Bool:
+			f.Method, f.Zero = "BoolVar", "false"
+		case types.Int:
+			f.Method = "IntVar"
+		case types.Int64:
+			f.Method = "Int64Var"
+		case types.Uint:
+			f.Method = "UintVar"
+		case types.Uint64:
+			f.Method = "Uint64Var"
+		case types.Float64:
+			f.Method = "Float64Var"
+		default:
+			return f, false
+		}
+		if _, named := t.(*types.Named); named {
+			f.Type = u.Name()
+		}
+		return f, true
+	case *types.Slice:
+		if _, named := t.(*types.Named); named {
+			return CommandFlag{}, false
+		}
+		switch {
+		case types.Identical(u.Elem(), types.Typ[types.String]):
+			return CommandFlag{Method: "StringSliceVar", Zero: "nil"}, true
+		case types.Identical(u.Elem(), types.Typ[types.Int]):
+			return CommandFlag{Method: "IntSliceVar", Zero: "nil"}, true
+		}
+	}
+	return CommandFlag{}, false
+}
+
+// kebabCase turns UserID into user-id.
+func kebabCase(name string) string {
+	runes := []rune(name)
+	var out []rune
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 &&
+			(unicode.IsLower(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+			out = append(out, '-')
+		}
+		out = append(out, unicode.ToLower(r))
+	}
+	return string(out)
+}
@@ -0,0 +1,307 @@
+package handlergen
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFile is the config file handler reads from the package directory
+// when no func is given.
+const ConfigFile = "handlers.yaml"
+
+// Config lists the funcs, encodings and options of a generation, as read
+// from a ConfigFile:
+//
+//	encodings: [encoding/json]
+//	funcs:
+//	  - name: PutJob
+//	    route: PUT /jobs
+//	  - name: ListJobs
+//	    route: GET /jobs
+//	    stream: ndjson
+//	adapters:
+//	  github.com/ugorji/go/codec:
+//	    decode: '{{.Pkg}}.NewDecoder({{.R}}, &{{.Pkg}}.CborHandle{}).Decode({{.V}})'
+//	    encode: '{{.Pkg}}.NewEncoder({{.W}}, &{{.Pkg}}.CborHandle{}).Encode({{.V}})'
+//	    content-type: application/cbor
+//	error-statuses:
+//	  ErrNotFound: 404
+//	  '*ValidationError': 422
+type Config struct {
+	Mode      string   `yaml:"mode"`      // "", consumer, command or job
+	Output    string   `yaml:"output"`    // relative to the config file, or - for stdout
+	Postman   string   `yaml:"postman"`   // relative to the config file
+	OpenAPI   string   `yaml:"openapi"`   // relative to the config file
+	Encodings []string `yaml:"encodings"` // of every func
+	Funcs     []Func   `yaml:"funcs"`
+	Exclude   []string `yaml:"exclude"` // names or patterns
+
+	WebSocket          bool     `yaml:"websocket"`
+	Name               string   `yaml:"name"`
+	Unexported         bool     `yaml:"unexported"`
+	Receiver           string   `yaml:"receiver"`
+	Interface          string   `yaml:"interface"`
+	Values             bool     `yaml:"values"`
+	RequestID          bool     `yaml:"request-id"`
+	Manifest           bool     `yaml:"manifest"`
+	URLs               bool     `yaml:"urls"`
+	Consts             bool     `yaml:"consts"`
+	Log                string   `yaml:"log"` // slog
+	Otel               bool     `yaml:"otel"`
+	Metrics            string   `yaml:"metrics"` // prometheus
+	PprofLabels        bool     `yaml:"pprof-labels"`
+	RateLimit          string   `yaml:"rate-limit"`     // like 10/s or 100/m:20
+	RateLimitKey       string   `yaml:"rate-limit-key"` // global, ip or header:<name>
+	Breaker            string   `yaml:"breaker"`        // like 5/30s
+	Idempotency        bool     `yaml:"idempotency"`
+	Auth               bool     `yaml:"auth"`
+	APIKey             string   `yaml:"api-key"` // header or query, optionally followed by :<name>
+	JWT                bool     `yaml:"jwt"`
+	JWTMethods         []string `yaml:"jwt-methods"` // like RS256
+	Sessions           bool     `yaml:"sessions"`
+	CSRF               bool     `yaml:"csrf"`
+	Stream             string   `yaml:"stream"`
+	Source             string   `yaml:"source"`
+	ContentType        string   `yaml:"content-type"`
+	ContentDisposition string   `yaml:"content-disposition"`
+	Charset            string   `yaml:"charset"` // reject or transcode
+	XMLHeader          bool     `yaml:"xml-header"`
+	XMLRoot            string   `yaml:"xml-root"`
+	Negotiate          bool     `yaml:"negotiate"`
+	DefaultEncoding    string   `yaml:"default-encoding"`
+	Problem            bool     `yaml:"problem"`
+	ErrorEncoder       string   `yaml:"error-encoder"`
+	ErrorHandler       string   `yaml:"error-handler"`
+	Envelope           bool     `yaml:"envelope"`
+	JSONAPI            bool     `yaml:"jsonapi"`
+	HAL                bool     `yaml:"hal"`
+	Pagination         bool     `yaml:"pagination"`
+	PageSize           int      `yaml:"page-size"`
+	MaxPageSize        int      `yaml:"max-page-size"`
+	Queue              string   `yaml:"queue"`
+	EnvPrefix          string   `yaml:"env-prefix"`
+	Template           string   `yaml:"template"`     // relative to the config file
+	TemplateDir        string   `yaml:"template-dir"` // relative to the config file
+	Hooks              []string `yaml:"hooks"`
+	TagsLine           string   `yaml:"tags-line"` // build constraint, like !nohandlers
+	Header             string   `yaml:"header"`    // relative to the config file
+
+	Adapters      map[string]Adapter `yaml:"adapters"`       // by import path of the encoding pkg
+	ErrorStatuses map[string]int     `yaml:"error-statuses"` // by error, like ErrNotFound: 404
+
+	dir string // Directory of the config file.
+}
+
+// ReadConfig reads the config file at path.
+func ReadConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &Config{dir: filepath.Dir(path)}
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return c, nil
+}
+
+// Path returns path relative to the directory of the config file, or ""
+// for an empty path.
+func (c *Config) Path(path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(c.dir, path)
+}
+
+// Configure adds the funcs and encodings of c to g, and sets the options
+// set in c on g, overriding the ones of g.
+func (c *Config) Configure(g *Generator) error {
+	if c.Mode != "" {
+		g.Mode = c.Mode
+	}
+	if c.Output == "-" {
+		g.Output = c.Output // Stdout.
+	} else if c.Output != "" {
+		g.Output = c.Path(c.Output)
+	}
+	if c.Postman != "" {
+		g.Postman = c.Path(c.Postman)
+	}
+	if c.OpenAPI != "" {
+		g.OpenAPI = c.Path(c.OpenAPI)
+	}
+	if c.WebSocket {
+		g.WebSocket = true
+	}
+	if c.Receiver != "" {
+		g.Receiver = c.Receiver
+	}
+	if c.Interface != "" {
+		g.Interface = c.Interface
+	}
+	if c.Values {
+		g.Values = true
+	}
+	if c.RequestID {
+		g.RequestID = true
+	}
+	if c.Manifest {
+		g.Manifest = true
+	}
+	if c.URLs {
+		g.URLs = true
+	}
+	if c.Consts {
+		g.Consts = true
+	}
+	if c.Log != "" {
+		g.Log = c.Log
+	}
+	if c.Otel {
+		g.Otel = true
+	}
+	if c.Metrics != "" {
+		g.Metrics = c.Metrics
+	}
+	if c.PprofLabels {
+		g.PprofLabels = true
+	}
+	if c.RateLimit != "" {
+		g.RateLimit = c.RateLimit
+	}
+	if c.RateLimitKey != "" {
+		g.RateLimitKey = c.RateLimitKey
+	}
+	if c.Breaker != "" {
+		g.Breaker = c.Breaker
+	}
+	if c.Idempotency {
+		g.Idempotency = true
+	}
+	if c.Auth {
+		g.Auth = true
+	}
+	if c.APIKey != "" {
+		g.APIKey = c.APIKey
+	}
+	if c.JWT {
+		g.JWT = true
+	}
+	if len(c.JWTMethods) > 0 {
+		g.JWTMethods = c.JWTMethods
+	}
+	if c.Sessions {
+		g.Sessions = true
+	}
+	if c.CSRF {
+		g.CSRF = true
+	}
+	if c.Unexported {
+		g.Unexported = true
+	}
+	if c.Name != "" {
+		g.Name = c.Name
+	}
+	if c.Stream != "" {
+		g.Stream = c.Stream
+	}
+	if c.Source != "" {
+		g.Source = c.Source
+	}
+	if c.ContentType != "" {
+		g.ContentType = c.ContentType
+	}
+	if c.ContentDisposition != "" {
+		g.ContentDisposition = c.ContentDisposition
+	}
+	if c.Charset != "" {
+		g.Charset = c.Charset
+	}
+	if c.XMLHeader {
+		g.XMLHeader = true
+	}
+	if c.XMLRoot != "" {
+		g.XMLRoot = c.XMLRoot
+	}
+	if c.Negotiate {
+		g.Negotiate = true
+	}
+	if c.DefaultEncoding != "" {
+		g.DefaultEncoding = c.DefaultEncoding
+	}
+	if c.Problem {
+		g.Problem = true
+	}
+	if c.ErrorEncoder != "" {
+		g.ErrorEncoder = c.ErrorEncoder
+	}
+	if c.ErrorHandler != "" {
+		g.ErrorHandler = c.ErrorHandler
+	}
+	if c.Envelope {
+		g.Envelope = true
+	}
+	if c.JSONAPI {
+		g.JSONAPI = true
+	}
+	if c.HAL {
+		g.HAL = true
+	}
+	if c.Pagination {
+		g.Pagination = true
+	}
+	if c.PageSize != 0 {
+		g.PageSize = c.PageSize
+	}
+	if c.MaxPageSize != 0 {
+		g.MaxPageSize = c.MaxPageSize
+	}
+	if c.Queue != "" {
+		g.Queue = c.Queue
+	}
+	if c.EnvPrefix != "" {
+		g.EnvPrefix = c.EnvPrefix
+	}
+	if c.TagsLine != "" {
+		g.BuildConstraint = c.TagsLine
+	}
+	if c.Header != "" {
+		header, err := ioutil.ReadFile(c.Path(c.Header))
+		if err != nil {
+			return err
+		}
+		g.Header = string(header)
+	}
+	if c.TemplateDir != "" {
+		g.TemplateDir = c.Path(c.TemplateDir)
+	}
+	for path, a := range c.Adapters {
+		if g.Adapters == nil {
+			g.Adapters = make(map[string]Adapter)
+		}
+		g.Adapters[path] = a
+	}
+	for name, status := range c.ErrorStatuses {
+		if g.ErrorStatuses == nil {
+			g.ErrorStatuses = make(map[string]int)
+		}
+		g.ErrorStatuses[name] = status
+	}
+	g.Exclude = append(g.Exclude, c.Exclude...)
+	if len(c.Hooks) > 0 {
+		g.Hooks = c.Hooks
+	}
+	if c.Template != "" {
+		if err := g.SetTemplate("handler", c.Path(c.Template)); err != nil {
+			return err
+		}
+	}
+	if err := g.AddEncoding(c.Encodings...); err != nil {
+		return err
+	}
+	return g.Add(c.Funcs...)
+}
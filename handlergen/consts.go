@@ -0,0 +1,48 @@
+package handlergen
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// RouteConst is the data the consts template declares the constants of a
+// route with, with the Consts option.
+type RouteConst struct {
+	Route      string // const of the path, like RoutePutJob
+	Path       string // quoted, like "/jobs/{id}"
+	Method     string // const of the method, like MethodPutJob, if the route has one
+	MethodName string // value of the Method, like http.MethodPut
+}
+
+// httpMethods are the consts of net/http naming the methods, by method.
+var httpMethods = map[string]string{
+	http.MethodGet:     "http.MethodGet",
+	http.MethodHead:    "http.MethodHead",
+	http.MethodPost:    "http.MethodPost",
+	http.MethodPut:     "http.MethodPut",
+	http.MethodPatch:   "http.MethodPatch",
+	http.MethodDelete:  "http.MethodDelete",
+	http.MethodConnect: "http.MethodConnect",
+	http.MethodOptions: "http.MethodOptions",
+	http.MethodTrace:   "http.MethodTrace",
+}
+
+// routeConsts returns the RouteConst of each of routes.
+func (g *Generator) routeConsts(routes []Route) []RouteConst {
+	var consts []RouteConst
+	for _, route := range routes {
+		c := RouteConst{
+			Route: g.exported("Route" + route.fn),
+			Path:  strconv.Quote(route.Path()),
+		}
+		if method := route.Method(); method != "" {
+			c.Method = g.exported("Method" + route.fn)
+			c.MethodName = httpMethods[method]
+			if c.MethodName == "" {
+				c.MethodName = strconv.Quote(method)
+			}
+		}
+		consts = append(consts, c)
+	}
+	return consts
+}
@@ -0,0 +1,18 @@
+package handlergen
+
+// buildConsumer generates a consumer of g.Queue messages for a single func
+// and encoding.
+func (g *Generator) buildConsumer(h interface{}) {
+	g.addImport("bytes")
+	name := "consumer_nats"
+	switch g.Queue {
+	case "", "nats":
+		g.addImport("github.com/nats-io/nats.go")
+	case "kafka":
+		g.addImport("context")
+		g.addImport("github.com/segmentio/kafka-go")
+		name = "consumer_kafka"
+	}
+
+	g.execute(name, h)
+}
@@ -0,0 +1,144 @@
+package handlergen
+
+import (
+	"errors"
+	"fmt"
+	"go/types"
+	"io"
+	"strings"
+)
+
+// CRUD is the data the crud templates are executed with: the funcs
+// creating, getting, listing, updating and deleting the resources of a
+// type, their routes, and the store they keep them in.
+type CRUD struct {
+	T          string // type of the resources, like Job
+	Var        string // name of the parameter of a resource, like j
+	ID         string // type of their ID, like string
+	IDString   bool   // the ID is of a string kind, else of an integer one
+	Collection string // path of the routes, like /jobs
+	Create     string // name of the func creating one, like CreateJob
+	Get        string // name of the func getting one by ID, like GetJob
+	List       string // name of the func listing them, like ListJobs
+	Update     string // name of the func replacing one by ID, like UpdateJob
+	Delete     string // name of the func deleting one by ID, like DeleteJob
+	Store      string // interface storing them, like JobStore
+	Memory     string // type storing them in memory, like MemoryJobStore
+	NewMemory  string // func returning a new Memory, like NewMemoryJobStore
+	Resources  string // package var of the Store the funcs use, like Jobs
+	NotFound   string // error of a Store having none of an ID, like ErrJobNotFound
+	Exists     string // error of a Store already having one of an ID, like ErrJobExists
+	Noun       string // naming a resource in the errors, like job
+}
+
+// crud returns the CRUD of the name type of the parsed package, a struct
+// stored by its ID field, of a string or integer type.
+func (g *Generator) crud(name string) (CRUD, error) {
+	if g.pkg == nil {
+		return CRUD{}, errors.New("no package parsed")
+	}
+	obj, ok := g.pkg.Types.Scope().Lookup(name).(*types.TypeName)
+	if !ok {
+		return CRUD{}, fmt.Errorf("type %s not found", name)
+	}
+	if _, ok := obj.Type().Underlying().(*types.Interface); ok {
+		return CRUD{}, fmt.Errorf("cannot store the resources of interface %s", name)
+	}
+	c := CRUD{
+		T:          name,
+		Var:        strings.ToLower(name[:1]),
+		Collection: "/" + resourceType(name),
+		Create:     "Create" + name,
+		Get:        "Get" + name,
+		List:       "List" + plural(name),
+		Update:     "Update" + name,
+		Delete:     "Delete" + name,
+		Store:      name + "Store",
+		Memory:     "Memory" + name + "Store",
+		NewMemory:  "NewMemory" + name + "Store",
+		Resources:  plural(name),
+		NotFound:   "Err" + name + "NotFound",
+		Exists:     "Err" + name + "Exists",
+		Noun:       strings.ToLower(name[:1]) + name[1:],
+	}
+	if st, ok := obj.Type().Underlying().(*types.Struct); ok {
+		for i := 0; i < st.NumFields(); i++ {
+			f := st.Field(i)
+			if f.Name() != "ID" {
+				continue
+			}
+			if u, ok := f.Type().Underlying().(*types.Basic); ok && u.Info()&(types.IsString|types.IsInteger) != 0 {
+				c.ID = types.TypeString(f.Type(), types.RelativeTo(g.pkg.Types))
+				c.IDString = u.Info()&types.IsString != 0
+			}
+		}
+	}
+	if c.ID == "" {
+		return CRUD{}, fmt.Errorf("cannot store the resources of %s: no ID field of a string or integer type", name)
+	}
+	return c, nil
+}
+
+// CRUDFuncs returns the funcs RenderCRUD declares for the name type, each
+// with its route, like POST /jobs or GET /jobs/{id}.
+func (g *Generator) CRUDFuncs(name string) ([]Func, error) {
+	c, err := g.crud(name)
+	if err != nil {
+		return nil, err
+	}
+	byID := map[string]string{"id": "path"}
+	return []Func{
+		{Name: c.Create, Route: "POST " + c.Collection},
+		{Name: c.Get, Route: "GET " + c.Collection + "/{id}", Params: byID},
+		{Name: c.List, Route: "GET " + c.Collection},
+		{Name: c.Update, Route: "PUT " + c.Collection + "/{id}", Params: byID},
+		{Name: c.Delete, Route: "DELETE " + c.Collection + "/{id}", Params: byID},
+	}, nil
+}
+
+// RenderCRUD writes the funcs creating, getting, listing, updating and
+// deleting the resources of the name type of the parsed package, keeping
+// them in its Store, each annotated with its route so that handler
+// generates their http handlers.
+func (g *Generator) RenderCRUD(w io.Writer, name string) error {
+	return g.renderCRUD(w, name, "crud")
+}
+
+// RenderCRUDStore writes the Store of the resources of the name type of the
+// parsed package, and the Memory implementing it, safe for concurrent use,
+// that the funcs of RenderCRUD use until replaced, like by a database.
+func (g *Generator) RenderCRUDStore(w io.Writer, name string) error {
+	return g.renderCRUD(w, name, "crud_store")
+}
+
+// RenderCRUDTest writes the tests of the Memory of RenderCRUDStore and of
+// the funcs of RenderCRUD using it.
+func (g *Generator) RenderCRUDTest(w io.Writer, name string) error {
+	return g.renderCRUD(w, name, "crud_test")
+}
+
+// renderCRUD writes the tmpl template executed with the CRUD of the name
+// type, unless the parsed package declares one of its names.
+func (g *Generator) renderCRUD(w io.Writer, name, tmpl string) error {
+	c, err := g.crud(name)
+	if err != nil {
+		return err
+	}
+	for _, decl := range []string{c.Create, c.Get, c.List, c.Update, c.Delete, c.Store, c.Memory, c.NewMemory, c.Resources, c.NotFound, c.Exists} {
+		if g.pkg.Types.Scope().Lookup(decl) != nil {
+			return fmt.Errorf("%s is already declared", decl)
+		}
+	}
+	g.buf.Reset()
+	g.imports = nil
+	g.names = nil
+	g.err = nil
+	g.Printf("package %s\n", g.pkg.Name)
+	g.execute(tmpl, c)
+	src := g.format()
+	if g.err != nil {
+		return g.err
+	}
+	_, err = w.Write(src)
+	return err
+}
@@ -0,0 +1,24 @@
+package handlergen
+
+// CSRF is the data the csrf templates are executed with, with the CSRF
+// option.
+type CSRF struct {
+	Cookie string // package var of the name of the cookie holding the token
+	Header string // package var of the name of the header the requests submit it in
+}
+
+// csrfNames returns the CSRF the declarations are executed with.
+func (g *Generator) csrfNames() CSRF {
+	return CSRF{
+		Cookie: g.exported("CSRFCookie"),
+		Header: g.exported("CSRFHeader"),
+	}
+}
+
+// checkCSRF returns the code of the http handlers responding 403 Forbidden
+// to the unsafe requests whose CSRF header does not match their cookie,
+// injected at their top, or else issuing the cookie to the safe ones.
+func (g *Generator) checkCSRF() string {
+	g.csrfToken = true
+	return g.code("csrf", nil)
+}
@@ -0,0 +1,85 @@
+package handlergen
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// csrfTest is the test of the http handler of PutJob, issuing a CSRF token
+// to the GET requests having none and responding 403 to the POST ones
+// missing it or submitting another one than their cookie.
+const csrfTest = `package jobs
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCSRF(t *testing.T) {
+	w := httptest.NewRecorder()
+	PutJobHandlerJSON(w, httptest.NewRequest("GET", "/jobs", strings.NewReader("{\"ID\": \"1\"}")))
+	if w.Code != 200 {
+		t.Fatalf("GET responded %d, want 200", w.Code)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != CSRFCookie || cookies[0].Value == "" || cookies[0].SameSite != http.SameSiteStrictMode {
+		t.Fatalf("GET issued cookies %v, want a SameSite Strict %s one", cookies, CSRFCookie)
+	}
+	token := cookies[0].Value
+
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/jobs", strings.NewReader("{\"ID\": \"1\"}"))
+	r.AddCookie(&http.Cookie{Name: CSRFCookie, Value: token})
+	PutJobHandlerJSON(w, r)
+	if cookies := w.Result().Cookies(); len(cookies) != 0 {
+		t.Errorf("GET having a token issued cookies %v, want none", cookies)
+	}
+
+	for _, test := range []struct {
+		name, cookie, header string
+		status               int
+	}{
+		{"no token", "", "", 403},
+		{"no cookie", "", token, 403},
+		{"no header", token, "", 403},
+		{"mismatched", token, "other", 403},
+		{"matching", token, token, 200},
+	} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/jobs", strings.NewReader("{\"ID\": \"1\"}"))
+		if test.cookie != "" {
+			r.AddCookie(&http.Cookie{Name: CSRFCookie, Value: test.cookie})
+		}
+		if test.header != "" {
+			r.Header.Set(CSRFHeader, test.header)
+		}
+		PutJobHandlerJSON(w, r)
+		if w.Code != test.status {
+			t.Errorf("POST with %s: responded %d, want %d", test.name, w.Code, test.status)
+		}
+	}
+}
+`
+
+func TestCSRF(t *testing.T) {
+	dir := testModule(t, map[string]string{"jobs.go": `package jobs
+
+type Job struct{ ID string }
+
+func PutJob(j Job) (Job, int) {
+	return j, 200
+}
+`})
+	g := &Generator{CSRF: true}
+	if err := generate(dir, g, []string{"PutJob"}, "encoding/json"); err != nil {
+		t.Fatal(err)
+	}
+	// Written once generated, not to be type-checked along the package.
+	if err := ioutil.WriteFile(filepath.Join(dir, "csrf_test.go"), []byte(csrfTest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	goTest(t, dir, g)
+}
@@ -0,0 +1,71 @@
+package handlergen
+
+import (
+	"go/types"
+	"strings"
+)
+
+// Cursor is the data the cursor template is executed with: the type of the
+// opaque cursors of a list, embedded in the parameter of the funcs listing
+// it, read from the cursor query param.
+type Cursor struct {
+	Encode, Decode string // names of the funcs declared, like EncodeJobCursorJSON
+	T              string // type of the cursor, qualified by its pkg name if needed
+	EncodingPkg    string // name of the encoding pkg the cursors are encoded with, before base64
+	Field          string // embedding the cursor in the parameter, like JobCursor
+	Var            string // holding the parameter in the handler, like x or param0
+}
+
+// cursorField returns the field of the t struct, or of the struct t points
+// to, embedding a cursor: a struct whose type is named like JobCursor.
+func cursorField(t types.Type) (string, *types.Named) {
+	named := namedType(t)
+	if named == nil {
+		return "", nil
+	}
+	st, ok := named.Underlying().(*types.Struct)
+	if !ok {
+		return "", nil
+	}
+	for i := 0; i < st.NumFields(); i++ {
+		field := st.Field(i)
+		cursor, ok := field.Type().(*types.Named)
+		if !field.Anonymous() || !field.Exported() || !ok || !strings.HasSuffix(cursor.Obj().Name(), "Cursor") {
+			continue
+		}
+		if _, ok := cursor.Underlying().(*types.Struct); ok {
+			return field.Name(), cursor
+		}
+	}
+	return "", nil
+}
+
+// bindCursor makes h read the cursor the parameter of type t held by v
+// embeds, if any, from the cursor query param, declaring the funcs encoding
+// and decoding it with the encoding pkg of h once.
+func (g *Generator) bindCursor(h *Handler, t types.Type, v string) {
+	field, cursor := cursorField(t)
+	if cursor == nil {
+		return
+	}
+	name := cursor.Obj().Name() + strings.ToUpper(h.EncodingPkg)
+	c := Cursor{
+		Encode:      g.exported("Encode" + name),
+		Decode:      g.exported("Decode" + name),
+		T:           types.TypeString(cursor, g.qualifier),
+		EncodingPkg: h.EncodingPkg,
+		Field:       field,
+		Var:         v,
+	}
+	h.Cursor = &c
+	if g.cursors[c.Decode] {
+		return
+	}
+	if g.cursors == nil {
+		g.cursors = make(map[string]bool)
+	}
+	g.cursors[c.Decode] = true
+	g.addImport("bytes")
+	g.addImport("encoding/base64")
+	g.execute("cursor", c)
+}
@@ -0,0 +1,363 @@
+package handlergen
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Eligible returns the names of the exported funcs of the parsed package, or
+// methods of the Receiver or Interface, whose signature is supported by the
+// Mode: F(x X) (resp, int) or (int, resp) for http handlers and commands,
+// F(x X) error for consumers and jobs. Http handlers can also call F()
+// (resp, int), or funcs taking parameters of basic types along at most one
+// other, like F(id int, x X) (resp, int).
+func (g *Generator) Eligible() []string {
+	var names []string
+	g.eachFunc(func(name *ast.Ident, ft *ast.FuncType) {
+		if g.unsupported(name, ft) == "" {
+			names = append(names, name.Name)
+		}
+	})
+	return names
+}
+
+// Skipped is a func Eligible leaves out.
+type Skipped struct {
+	Func   string // name of the func
+	Pos    string // where it is declared, like jober.go:10:6
+	Reason string // why its signature is not supported
+}
+
+// Skipped returns the exported funcs of the parsed package, or methods of
+// the Receiver or Interface, that Eligible leaves out.
+func (g *Generator) Skipped() []Skipped {
+	var skipped []Skipped
+	g.eachFunc(func(name *ast.Ident, ft *ast.FuncType) {
+		if reason := g.unsupported(name, ft); reason != "" {
+			skipped = append(skipped, Skipped{
+				Func:   name.Name,
+				Pos:    g.pkg.Fset.Position(name.Pos()).String(),
+				Reason: reason,
+			})
+		}
+	})
+	return skipped
+}
+
+// eachFunc calls fn with the exported funcs of the parsed package, or
+// methods of the Receiver or Interface.
+func (g *Generator) eachFunc(fn func(name *ast.Ident, ft *ast.FuncType)) {
+	if g.pkg == nil {
+		return
+	}
+	if g.Interface != "" {
+		t := g.interfaceType()
+		if t == nil {
+			return
+		}
+		methods, funcs, _ := interfaceMethods(t)
+		for i, name := range methods {
+			if name.IsExported() {
+				fn(name, funcs[i])
+			}
+		}
+		return
+	}
+	for _, file := range g.pkg.Files {
+		for _, decl := range file.Decls {
+			decl, ok := decl.(*ast.FuncDecl)
+			if ok && recvName(decl) == g.Receiver && decl.Name.IsExported() {
+				fn(decl.Name, decl.Type)
+			}
+		}
+	}
+}
+
+// fileOf returns the name of the source file declaring the func named name,
+// if found.
+func (g *Generator) fileOf(name string) string {
+	d, ok := g.decls[declKey(g.Receiver+g.Interface, name)]
+	if !ok {
+		return ""
+	}
+	return g.pkg.Fset.Position(d.name.Pos()).Filename
+}
+
+// declaration returns where the func named name is declared, like
+// jober.go:10:6, and its signature, like PutJob(j Job) (interface{}, int) or
+// Server.PutJob(j Job) (interface{}, int) for a method, if found.
+func (g *Generator) declaration(name string) (pos, signature string) {
+	key := declKey(g.Receiver+g.Interface, name)
+	d, ok := g.decls[key]
+	if !ok {
+		return "", ""
+	}
+	return g.pkg.Fset.Position(d.name.Pos()).String(), key + strings.TrimPrefix(types.ExprString(d.ft), "func")
+}
+
+// unsupported returns why the func named name of type ft does not have a
+// supported signature, if it does not.
+func (g *Generator) unsupported(name *ast.Ident, ft *ast.FuncType) string {
+	if ft.TypeParams != nil {
+		return "has type parameters: instantiate it"
+	}
+	fn, ok := g.pkg.Defs[name].(*types.Func)
+	if !ok {
+		return "has no type information"
+	}
+	sig := fn.Type().(*types.Signature)
+	switch {
+	case sig.Params().Len() == 0 && g.Mode != "":
+		return "takes no parameter: only http handlers can call it"
+	case sig.Params().Len() > 1 && g.Mode != "":
+		return "takes several parameters: only http handlers bind them"
+	}
+	body, i := 0, 0
+	for _, field := range ft.Params.List {
+		param := field.Type
+		if star, ok := param.(*ast.StarExpr); ok {
+			param = star.X
+		}
+		switch param.(type) {
+		case *ast.Ident, *ast.SelectorExpr, *ast.IndexExpr, *ast.IndexListExpr:
+		case *ast.ArrayType, *ast.MapType:
+			if param != field.Type {
+				return "takes a pointer to a slice or map"
+			}
+		default:
+			return fmt.Sprintf("takes a %s", types.ExprString(field.Type))
+		}
+		for range max(len(field.Names), 1) {
+			switch v := sig.Params().At(i); {
+			case !basic(v.Type()):
+				body++
+			case sig.Params().Len() > 1 && (v.Name() == "" || v.Name() == "_"):
+				return fmt.Sprintf("parameter %d needs a name to be read from the request", i)
+			}
+			i++
+		}
+	}
+	if body > 1 {
+		return "takes several parameters that are not of a basic type: only one can be decoded from the body"
+	}
+	if err := g.checkResults(sig.Results()); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// IsPattern reports whether name is a pattern, like Put*, rather than a func
+// name.
+func IsPattern(name string) bool {
+	return strings.ContainsAny(name, `*?[]()|^$.+{}\`)
+}
+
+// matcher returns the func reporting whether a name matches pattern: a glob
+// like Put* if it only holds *, ? or [] as special characters, a regular
+// expression matching whole names like Handle.* otherwise. A name that is not
+// a pattern only matches itself.
+func matcher(pattern string) (func(name string) bool, error) {
+	if !IsPattern(pattern) {
+		return func(name string) bool { return name == pattern }, nil
+	}
+	if _, err := path.Match(pattern, ""); err == nil && !strings.ContainsAny(pattern, `()|^$.+{}\`) {
+		return func(name string) bool {
+			ok, _ := path.Match(pattern, name)
+			return ok
+		}, nil
+	}
+	re, err := regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid func pattern %s: %s", pattern, err)
+	}
+	return re.MatchString, nil
+}
+
+// match returns the eligible funcs matching pattern.
+func (g *Generator) match(pattern string) ([]string, error) {
+	matches, err := matcher(pattern)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, name := range g.Eligible() {
+		if matches(name) {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// method matches the name of a method, like (*Server).PutJob or
+// (Server).Put*.
+var method = regexp.MustCompile(`^\(\*?([A-Za-z_][A-Za-z_0-9]*)\)\.(.+)$`)
+
+// methodFuncs sets the Receiver to the type of the funcs named after a
+// method, renaming them after the method: they all must be methods of the
+// same type, and the other funcs are then methods of it too.
+func (g *Generator) methodFuncs() error {
+	receiver, plain := g.Receiver, ""
+	for i, fn := range g.funcs {
+		m := method.FindStringSubmatch(fn.Name)
+		if m == nil {
+			plain = fn.Name
+			continue
+		}
+		if receiver != "" && m[1] != receiver {
+			return fmt.Errorf("%s is not a method of %s", fn.Name, receiver)
+		}
+		receiver = m[1]
+		g.funcs[i].Name = m[2]
+	}
+	if receiver != g.Receiver && plain != "" {
+		return fmt.Errorf("cannot generate for func %s along methods of %s", plain, receiver)
+	}
+	g.Receiver = receiver
+	return nil
+}
+
+// expandFuncs returns the funcs to generate for, replacing the ones named
+// by a pattern by the eligible funcs it matches, without the Exclude ones.
+func (g *Generator) expandFuncs() ([]Func, error) {
+	var excludes []func(name string) bool
+	for _, pattern := range g.Exclude {
+		exclude, err := matcher(pattern)
+		if err != nil {
+			return nil, err
+		}
+		excludes = append(excludes, exclude)
+	}
+	excluded := func(name string) bool {
+		for _, exclude := range excludes {
+			if exclude(name) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var funcs []Func
+	seen := make(map[string]bool)
+	for _, fn := range g.funcs {
+		if !IsPattern(fn.Name) {
+			if g.Partial {
+				if pos, _ := g.declaration(fn.Name); pos == "" {
+					g.logf("%s is not declared in %s, skipped", fn.Name, g.pkg.Dir)
+					continue
+				}
+			}
+			if !excluded(fn.Name) {
+				funcs = append(funcs, fn)
+			} else {
+				g.logf("%s is excluded", fn.Name)
+			}
+			continue
+		}
+		names, err := g.match(fn.Name)
+		if err != nil {
+			return nil, err
+		}
+		if len(names) == 0 && g.Partial {
+			g.logf("%s matches no func in %s", fn.Name, g.pkg.Dir)
+		} else if len(names) == 0 {
+			if g.Strict {
+				return nil, fmt.Errorf("%s matches no func of a supported signature", fn.Name)
+			}
+			log.Printf("warning: %s matches no func of a supported signature", fn.Name)
+		} else {
+			g.logf("%s matches %s", fn.Name, strings.Join(names, ", "))
+		}
+		for _, name := range names {
+			if seen[name] || excluded(name) {
+				if !seen[name] {
+					g.logf("%s is excluded", name)
+				}
+				continue
+			}
+			seen[name] = true
+			matched := fn
+			matched.Name = name
+			funcs = append(funcs, matched)
+		}
+	}
+	if g.File != "" {
+		var kept []Func
+		for _, fn := range funcs {
+			if g.fileOf(fn.Name) == g.File {
+				kept = append(kept, fn)
+			} else {
+				g.logf("%s is not declared in %s, skipped", fn.Name, g.File)
+			}
+		}
+		funcs = kept
+	}
+	// Generate in the same order whatever the order funcs were given in.
+	sort.SliceStable(funcs, func(i, j int) bool {
+		if funcs[i].Name != funcs[j].Name {
+			return funcs[i].Name < funcs[j].Name
+		}
+		return strings.Join(funcs[i].TypeArgs, ",") < strings.Join(funcs[j].TypeArgs, ",")
+	})
+	return funcs, nil
+}
+
+// Planned is a func Render generates for.
+type Planned struct {
+	Func      string   // name of the func
+	T         string   // types of its parameters, qualified by their pkg name if needed
+	Encodings []string // import paths of the encoding pkgs generated for
+	Route     string   // pattern RegisterHandlers registers the handler on, if any
+	File      string   // name of the source file declaring the func
+}
+
+// Plan returns the funcs Render would generate for, without generating
+// anything. It fails if a func is not found, unless AllowMissing leaves it
+// out.
+func (g *Generator) Plan() ([]Planned, error) {
+	if g.pkg == nil {
+		return nil, fmt.Errorf("no package parsed")
+	}
+	if err := g.methodFuncs(); err != nil {
+		return nil, err
+	}
+	funcs, err := g.expandFuncs()
+	if err != nil {
+		return nil, err
+	}
+	var plan []Planned
+	for _, fn := range funcs {
+		encodings, err := g.funcEncodings(fn)
+		if err != nil {
+			return nil, err
+		}
+		sig, found := g.lookup(fn)
+		if !found {
+			if !g.AllowMissing || g.Strict {
+				return nil, fmt.Errorf("func %s not found", fn.Name)
+			}
+			log.Printf("warning: func %s not found, skipped", fn.Name)
+			continue
+		}
+		p := Planned{
+			Func:  fn.Name + typeList(sig.targs, g.qualifier),
+			Route: fn.Route,
+			File:  g.fileOf(fn.Name),
+		}
+		var params []string
+		for _, param := range sig.params {
+			params = append(params, param.fullname)
+		}
+		p.T = strings.Join(params, ", ")
+		for _, encoding := range encodings {
+			p.Encodings = append(p.Encodings, encoding.path)
+		}
+		plan = append(plan, p)
+	}
+	return plan, nil
+}
@@ -0,0 +1,110 @@
+package handlergen
+
+import (
+	"fmt"
+	"go/types"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// ErrorStatus is a status the http handlers respond when the func returns
+// an error matching, from the ErrorStatuses.
+type ErrorStatus struct {
+	Match  string // condition on err, like errors.Is(err, ErrNotFound)
+	Status int
+	Text   string // of the status, like Not Found
+}
+
+// checkErrorStatuses checks that the ErrorStatuses map errors to statuses.
+func (g *Generator) checkErrorStatuses() error {
+	for _, name := range g.errorNames() {
+		if _, err := g.lookupError(name); err != nil {
+			return err
+		}
+		if status := g.ErrorStatuses[name]; http.StatusText(status) == "" {
+			return fmt.Errorf("invalid status %d of error %s", status, name)
+		}
+	}
+	return nil
+}
+
+// errorNames returns the names of the ErrorStatuses, sorted.
+func (g *Generator) errorNames() []string {
+	var names []string
+	for name := range g.ErrorStatuses {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookupError returns the var, or the type name, of the error named name in
+// the ErrorStatuses, like ErrNotFound, *ValidationError or io.EOF.
+func (g *Generator) lookupError(name string) (types.Object, error) {
+	pkg, ident := g.pkg.Types, strings.TrimPrefix(name, "*")
+	if i := strings.LastIndex(ident, "."); i >= 0 {
+		pkg = nil
+		for _, p := range g.pkg.Types.Imports() {
+			if p.Path() == ident[:i] || p.Name() == ident[:i] {
+				pkg = p
+				break
+			}
+		}
+		if pkg == nil {
+			return nil, fmt.Errorf("error %s: package %s is not imported by package %s", name, ident[:i], g.pkg.Name)
+		}
+		ident = ident[i+1:]
+	}
+	errorType := types.Universe.Lookup("error").Type()
+	switch obj := pkg.Scope().Lookup(ident).(type) {
+	case *types.Var:
+		if strings.HasPrefix(name, "*") || !types.AssignableTo(obj.Type(), errorType) {
+			return nil, fmt.Errorf("error %s is not an error", name)
+		}
+		return obj, nil
+	case *types.TypeName:
+		t := obj.Type()
+		if strings.HasPrefix(name, "*") {
+			t = types.NewPointer(t)
+		}
+		if !types.Implements(t, errorType.Underlying().(*types.Interface)) {
+			return nil, fmt.Errorf("error type %s does not implement error", name)
+		}
+		return obj, nil
+	}
+	return nil, fmt.Errorf("error %s not found", name)
+}
+
+// errorStatuses returns the ErrorStatuses, in the order of their names, as
+// the http handlers match them.
+func (g *Generator) errorStatuses() []ErrorStatus {
+	var statuses []ErrorStatus
+	for _, name := range g.errorNames() {
+		obj, err := g.lookupError(name)
+		if err != nil {
+			continue // Reported by checkErrorStatuses.
+		}
+		var match string
+		switch obj := obj.(type) {
+		case *types.Var:
+			ident := obj.Name()
+			if q := g.qualifier(obj.Pkg()); q != "" {
+				ident = q + "." + ident
+			}
+			match = "errors.Is(err, " + ident + ")"
+		case *types.TypeName:
+			t := obj.Type()
+			if strings.HasPrefix(name, "*") {
+				t = types.NewPointer(t)
+			}
+			match = "errors.As(err, new(" + types.TypeString(t, g.qualifier) + "))"
+		}
+		status := g.ErrorStatuses[name]
+		statuses = append(statuses, ErrorStatus{Match: match, Status: status, Text: http.StatusText(status)})
+	}
+	if len(statuses) > 0 {
+		g.addImport("errors")
+	}
+	return statuses
+}
@@ -0,0 +1,72 @@
+package handlergen
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// eventsTest is the test of the http handler of WatchJob, streaming the
+// events of the chan it returns along a 2xx status, or writing the status
+// alone.
+const eventsTest = `package jobs
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestEvents(t *testing.T) {
+	for _, test := range []struct {
+		id          string
+		status      int
+		body        string
+		contentType string
+	}{
+		{"1", 200, "data: {\"ID\":\"1\"}\n\ndata: {\"ID\":\"2\"}\n\n", "text/event-stream"},
+		{"none", 200, "", ""}, // nil
+		{"missing", 404, "", ""},
+		{"down", 503, "", ""},
+	} {
+		w := httptest.NewRecorder()
+		WatchJobHandlerJSON(w, httptest.NewRequest("POST", "/jobs", strings.NewReader("{\"ID\": \""+test.id+"\"}")))
+		if w.Code != test.status || w.Body.String() != test.body || w.Header().Get("Content-Type") != test.contentType {
+			t.Errorf("job %s: responded %d %q, of type %q, want %d %q, of type %q", test.id, w.Code, w.Body, w.Header().Get("Content-Type"), test.status, test.body, test.contentType)
+		}
+	}
+}
+`
+
+func TestEventsWithoutStream(t *testing.T) {
+	dir := testModule(t, map[string]string{"jobs.go": `package jobs
+
+type Job struct{ ID string }
+
+func WatchJob(j Job) (<-chan Job, int) {
+	switch j.ID {
+	case "none":
+		return nil, 200
+	case "missing":
+		return nil, 404
+	}
+	events := make(chan Job, 2)
+	events <- Job{ID: "1"}
+	events <- Job{ID: "2"}
+	close(events)
+	if j.ID == "down" {
+		return events, 503
+	}
+	return events, 200
+}
+`})
+	g := &Generator{}
+	if err := generate(dir, g, []string{"WatchJob"}, "encoding/json"); err != nil {
+		t.Fatal(err)
+	}
+	// Written once generated, not to be type-checked along the package.
+	if err := ioutil.WriteFile(filepath.Join(dir, "events_test.go"), []byte(eventsTest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	goTest(t, dir, g)
+}
@@ -0,0 +1,125 @@
+package handlergen
+
+import (
+	"go/types"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Filter is the data the filter template is executed with: a struct
+// parameter whose fields carry filter tags, read from the query.
+type Filter struct {
+	Name    string // of the func parsing it, like ParseJobFilter
+	T       string // type of the struct, qualified by its pkg name if needed
+	Fields  []FilterField
+	Filters string // filter[...] query params the fields are read from, like "filter[status]", "filter[owner]"
+	Allowed bool   // a field only accepts the values its tag lists
+}
+
+// FilterField is a field of a Filter, read from a query param. Its filter
+// tag names the param, sort, fields or a filter[name] one, along with the
+// values it accepts, if only some, like `filter:"status=open,closed"`.
+type FilterField struct {
+	Key     string // query param, like filter[status], sort or fields
+	Field   string // Go name of the field
+	Parse   string // call parsing v into (parsed, err); none for strings
+	Type    string // the field is converted to, if needed
+	Split   bool   // comma-separated []string
+	Allowed string // values accepted, like "open", "closed"; default any
+	Sort    bool   // the values may be prefixed by - to sort in descending order
+}
+
+// isFilter reports whether t is a struct, or points to one, having a field
+// with a filter tag.
+func isFilter(t types.Type) bool {
+	named := namedType(t)
+	if named == nil {
+		return false
+	}
+	st, ok := named.Underlying().(*types.Struct)
+	if !ok {
+		return false
+	}
+	for i := 0; i < st.NumFields(); i++ {
+		if _, ok := reflect.StructTag(st.Tag(i)).Lookup("filter"); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// filter returns the name of the func parsing the filter parameter of type
+// t from the query, declaring it once for every handler taking one.
+func (g *Generator) filter(funcName string, t types.Type) (string, bool) {
+	named := namedType(t)
+	f := Filter{
+		Name: g.exported("Parse" + named.Obj().Name()),
+		T:    types.TypeString(named, g.qualifier),
+	}
+	for _, declared := range g.filters {
+		if declared.Name == f.Name && declared.T != f.T {
+			g.errorf("%s: %s parses both %s and %s", funcName, f.Name, declared.T, f.T)
+			return "", false
+		}
+		if declared.Name == f.Name {
+			return f.Name, true
+		}
+	}
+	var filters []string
+	st := named.Underlying().(*types.Struct)
+	for i := 0; i < st.NumFields(); i++ {
+		field := st.Field(i)
+		tag, ok := reflect.StructTag(st.Tag(i)).Lookup("filter")
+		if !ok || tag == "-" {
+			continue
+		}
+		if !field.Exported() {
+			g.errorf("%s: filter field %s of %s is not exported", funcName, field.Name(), f.T)
+			return "", false
+		}
+		key, values := tag, ""
+		if i := strings.Index(tag, "="); i >= 0 {
+			key, values = tag[:i], tag[i+1:]
+		}
+		if key == "" {
+			key = kebabCase(field.Name())
+		}
+		ff, ok := g.envField(field.Type(), "v")
+		if !ok {
+			g.errorf("%s: cannot read filter field %s of %s, a %s, from the query", funcName, field.Name(), f.T, types.TypeString(field.Type(), g.qualifier))
+			return "", false
+		}
+		switch key {
+		case "sort", "fields":
+			if !ff.Split {
+				g.errorf("%s: %s field %s of %s is not a []string", funcName, key, field.Name(), f.T)
+				return "", false
+			}
+		default:
+			key = "filter[" + key + "]"
+			filters = append(filters, strconv.Quote(key))
+		}
+		var allowed []string
+		for _, v := range strings.Split(values, ",") {
+			if v != "" {
+				allowed = append(allowed, strconv.Quote(v))
+			}
+		}
+		f.Allowed = f.Allowed || len(allowed) > 0
+		f.Fields = append(f.Fields, FilterField{
+			Key:     key,
+			Field:   field.Name(),
+			Parse:   ff.Parse,
+			Type:    ff.Type,
+			Split:   ff.Split,
+			Allowed: strings.Join(allowed, ", "),
+			Sort:    key == "sort",
+		})
+	}
+	f.Filters = strings.Join(filters, ", ")
+	g.addImport("fmt")
+	g.addImport("strings")
+	g.filters = append(g.filters, f)
+	return f.Name, true
+}
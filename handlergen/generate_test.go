@@ -0,0 +1,97 @@
+package handlergen
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testModule writes files to a new module, example.com/jobs, requiring the
+// modules of requires, like "github.com/tinylib/msgp v1.6.4", and returns its
+// directory, made the current one until the test ends so that the encoding
+// pkgs resolve in it. It skips the test if the go command or the modules
+// are not available.
+func testModule(t *testing.T, files map[string]string, requires ...string) string {
+	t.Helper()
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("no go command to build the generated code with")
+	}
+	dir := t.TempDir()
+	mod := "module example.com/jobs\n\ngo 1.22\n"
+	for _, require := range requires {
+		mod += "\nrequire " + require + "\n"
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte(mod), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for name, src := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(requires) > 0 {
+		args := []string{"get"}
+		for _, require := range requires {
+			args = append(args, strings.Replace(require, " ", "@", 1))
+		}
+		if out, err := goCommand(dir, args...); err != nil {
+			t.Skipf("cannot download %s: %s\n%s", strings.Join(requires, ", "), err, out)
+		}
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	return dir
+}
+
+// generate writes the code g generates for the package of dir, with the
+// funcs and encodings added, to dir/generated_handlers.go, or
+// dir/generated_handlers_test.go for the test files.
+func generate(dir string, g *Generator, funcs []string, encodings ...string) error {
+	g.AddFunc(funcs...)
+	if err := g.AddEncoding(encodings...); err != nil {
+		return err
+	}
+	if err := g.Parse(dir); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := g.Render(&buf); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, generated(g)), buf.Bytes(), 0644)
+}
+
+// generated returns the name of the file generate writes the code of g to.
+func generated(g *Generator) string {
+	if g.Tests != "" {
+		return "generated_handlers_test.go"
+	}
+	return "generated_handlers.go"
+}
+
+// goTest runs the tests of the package of dir, failing with their output
+// and the code g generated.
+func goTest(t *testing.T, dir string, g *Generator) {
+	t.Helper()
+	if out, err := goCommand(dir, "test", "."); err != nil {
+		src, _ := ioutil.ReadFile(filepath.Join(dir, generated(g)))
+		t.Fatalf("go test: %s\n%s\n%s:\n%s", err, out, generated(g), src)
+	}
+}
+
+// goCommand runs the go command with args in dir, returning its output.
+func goCommand(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+	return cmd.CombinedOutput()
+}
@@ -0,0 +1,2174 @@
+// Package handlergen generates typed http handlers calling the funcs of a
+// package, or message queue consumers, cobra commands and jobs calling them.
+//
+// It is what the handler command runs, see the README.md of
+// github.com/azr/generators/handler for what is generated.
+// Build tools can use it without shelling out:
+//
+//  g := handlergen.Generator{By: "mytool"}
+//  g.AddFunc("PutJob")
+//  err := g.AddEncoding("encoding/json")
+//  ...
+//  err = g.Parse("./jober")
+//  ...
+//  err = g.Render(w)
+package handlergen // import "github.com/azr/generators/handlergen"
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/build/constraint"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/azr/generators/loader"
+	"github.com/azr/generators/utils"
+	"golang.org/x/tools/imports"
+)
+
+// Generator holds the state of the analysis. Primarily used to buffer
+// the output for imports.Process.
+//
+// Its exported fields are the generation options, to be set before Render.
+type Generator struct {
+	// Mode is what is generated: "" for http handlers, "consumer",
+	// "command", "job" or "health", for /healthz and /readyz handlers of
+	// each encoding running the Checkers registered, rather than handlers
+	// of funcs.
+	Mode string
+
+	// By is credited in the "Code generated by" header.
+	// Default is handlergen.
+	By string
+
+	// Version of the tool credited along By, like v1.2.0, if known.
+	Version string
+
+	// Header is printed at the top of the generated files, before the
+	// "Code generated by" header, like a license; its lines are commented
+	// unless it starts with a comment.
+	Header string
+
+	// Output is the file the output will be written to, as told to hooks, or
+	// - for stdout.
+	Output string
+
+	// Postman is the file the handler command writes the Postman collection
+	// of the routes to along, with RenderPostman, if any.
+	Postman string
+
+	// OpenAPI is the file the handler command writes the OpenAPI spec of the
+	// routes to along, with RenderOpenAPI, if any. RegisterHandlers then
+	// also serves it, with a Swagger UI at /docs.
+	OpenAPI string
+
+	// BuildConstraint is the //go:build constraint of the generated files,
+	// like !nohandlers, to compile them out of some builds. Default is none.
+	BuildConstraint string
+
+	// Name is a text/template of the names of the handler funcs, executed
+	// with the Func and its Encoding pkg name in upper case, like
+	// Handle{{.Func}}. Default is {{.Func}}Handler{{.Encoding}}.
+	Name string
+
+	// Unexported lowercases the first letter of the generated funcs, like
+	// putJobHandlerJSON.
+	Unexported bool
+
+	// Values also declares each http handler as an http.Handler, like
+	// PutJobJSON, to compose with middleware chains; with a Receiver, a
+	// method returns it instead.
+	Values bool
+
+	// RequestID declares WithRequestID, a middleware giving each request the
+	// ID its X-Request-Id header tells, or a random one, stored in its
+	// context, where RequestID reads it, and set on the response.
+	// RegisterHandlers wraps the handlers it registers with it.
+	RequestID bool
+
+	// Manifest declares Routes, returning a RouteInfo for each route
+	// RegisterHandlers registers: its method, path and handler, and the
+	// types of the body decoded and of the responses.
+	Manifest bool
+
+	// URLs declares a func building the paths of each route from the
+	// parameters of the func read from its wildcards, like PutJobURL(id)
+	// for PUT /jobs/{id}, not to hard-code them.
+	URLs bool
+
+	// Consts declares the constants of the path and method of each route,
+	// like RoutePutJob = "/jobs/{id}" and MethodPutJob = http.MethodPut, to
+	// refer to them.
+	Consts bool
+
+	// Log wraps the http handlers with structured logging, with log/slog
+	// when set to slog: they log their requests once responded, with their
+	// method, path, status and latency, and the errors of the requests they
+	// cannot decode. They log with the Logger *slog.Logger field of the
+	// Receiver if it has one, with the HandlerLogger package var declared
+	// along otherwise; the default logger if nil.
+	Log string
+
+	// Otel traces the http handlers with OpenTelemetry, from the global
+	// tracer provider: each starts a span of its own, a child of the one of
+	// the request, like the server span of otelhttp, with the ones of the
+	// decoding of the request, the call of the func and the encoding of the
+	// response, recording the status, the error the func returns and the
+	// ones of the requests they cannot decode; a 5xx status is an error.
+	Otel bool
+
+	// Metrics measures the http handlers with Prometheus when set to
+	// prometheus: they count their requests and time them once responded, by
+	// handler and status class, like 2xx, in metrics RegisterMetrics
+	// registers.
+	Metrics string
+
+	// PprofLabels calls the funcs of the http handlers in pprof.Do, labeled
+	// with the name of the handler and of its encoding, like handler
+	// PutJobHandlerJSON and encoding json, so the CPU profiles of the server
+	// attribute the time spent calling them to their endpoint.
+	PprofLabels bool
+
+	// RateLimit limits the requests of the http handlers of each func, shared
+	// by its encodings, with golang.org/x/time/rate: requests per s, m or h,
+	// optionally followed by the burst, like 10/s or 100/m:20; the burst is
+	// the number of requests otherwise. Requests over it are responded 429
+	// Too Many Requests, with a Retry-After header. A Func can set its own.
+	RateLimit string
+
+	// RateLimitKey is what requests are limited by: global, the default, ip
+	// for a limiter per client IP, or header:<name> for one per value of the
+	// header, like header:X-API-Key. A Func can set its own.
+	RateLimitKey string
+
+	// Breaker wraps the calls of each func in a circuit breaker, shared by
+	// its http handlers: set to the number of 5xx statuses in a row opening
+	// it, optionally followed by the time it stays open, a minute otherwise,
+	// like 5/30s. While open, the handlers respond 503 Service Unavailable,
+	// with a Retry-After header, rather than calling the func; then a single
+	// request tries it, closing the breaker if it succeeds. A Func can set
+	// its own.
+	Breaker string
+
+	// Idempotency makes the http handlers replay the response to a request
+	// having an Idempotency-Key header that a previous one having the same
+	// key got, from the IdempotencyStore HandlerIdempotency, rather than
+	// calling their func again, like for payments: 409 Conflict while that
+	// one is in progress, 422 Unprocessable Entity if the body differs. The
+	// keys are scoped to the principal, with Auth, and to the API key, with
+	// APIKey. A 5xx response is not stored, nor its cookies. The handlers
+	// streaming their responses do not replay them. A Func can set it too.
+	Idempotency bool
+
+	// Auth declares the Authenticator interface, authenticating each
+	// request of the http handlers with the one HandlerAuthenticator holds
+	// before anything else but rate limiting: they respond 401 Unauthorized
+	// to the ones it fails, or to every one while nil, and put who the
+	// others are authenticated as, a Principal, in their context, where
+	// RequestPrincipal reads it. The parsed package can declare Principal.
+	// A Func can be Public.
+	Auth bool
+
+	// APIKey makes the http handlers check the API key of each request,
+	// read from where it tells: header, X-API-Key, or query, api_key,
+	// optionally followed by the name, like header:Authorization. The
+	// APIKeyValidator HandlerAPIKeys holds validates it: they respond 401
+	// Unauthorized if the key is missing or invalid, or until it is set,
+	// and 403 Forbidden if it is not allowed to call the handler. A Func can
+	// set its own.
+	APIKey string
+
+	// JWT makes the http handlers read the parameters of a type named like
+	// Claims, a jwt.Claims like a struct embedding jwt.RegisteredClaims,
+	// from the bearer token of the Authorization header, verified with
+	// github.com/golang-jwt/jwt/v5 and the key JWTKeyfunc returns: they
+	// respond 401 Unauthorized to the requests whose token is missing or
+	// invalid.
+	JWT bool
+
+	// JWTMethods are the signing methods the bearer tokens can be signed
+	// with, like RS256. Default is the ones of the kind of key JWTKeyfunc
+	// returns, like HS256, HS384 and HS512 for a []byte.
+	JWTMethods []string
+
+	// Sessions declares Session, unless the parsed package declares it, and
+	// the SessionStore interface: the http handlers of the funcs taking a
+	// *Session load it from the one HandlerSessions holds, by the ID of the
+	// session cookie of the request, responding 401 Unauthorized to the
+	// requests having none, and save it once the func succeeds, with a
+	// status below 400. The funcs can take it once the generator ran.
+	Sessions bool
+
+	// CSRF makes the http handlers check the CSRF token of the unsafe
+	// requests, like the POSTs of the forms of browsers, double-submitted:
+	// the token of the CSRFCookie cookie, with SameSite Strict, issued to
+	// the GET requests having none, must be submitted in the CSRFHeader
+	// header too, or they respond 403 Forbidden. A Func can set it too.
+	CSRF bool
+
+	// Receiver is the type the funcs are methods of, like Server: handlers
+	// then are methods of *Server too, calling s.F. It cannot go along Package.
+	Receiver string
+
+	// Interface is an interface of the parsed package, like JobAPI, to
+	// generate for the methods of: handlers then are methods of a type
+	// embedding it, and NewJobAPIHandlers returns them in a struct of
+	// http.HandlerFuncs, JobAPIHandlers. It cannot go along Receiver.
+	Interface string
+
+	// Tests also parses the _test.go files of the package, for funcs only
+	// tests use: "test" for the ones of the package, "xtest" for the ones
+	// of its external test package, like foo_test, alone. The package must
+	// then be parsed from its directory. Default is none.
+	Tests string
+
+	// Package is the name of the package generated into, when it is not the
+	// package of the funcs: the generated code then imports it, qualifying
+	// its funcs and types. It must have another name.
+	Package string
+
+	WebSocket bool   // Also generate WebSocket endpoints.
+	Stream    string // Streaming format of slices and chans: "" or ndjson.
+
+	// Source is where http handlers read a parameter of a basic type, like
+	// F(id int), from: "" for the body, query, path or header for the query
+	// value, path value or header named after the parameter, id. Along other
+	// parameters, it is read from the query by default.
+	Source string
+
+	// Headers of io.Reader and []byte responses.
+	// ContentType defaults to application/octet-stream.
+	ContentType, ContentDisposition string
+
+	// Charset is what the http handlers do with the request bodies of another
+	// charset than UTF-8, as told by their Content-Type: "" to decode them as
+	// is, reject to respond 415 Unsupported Media Type, or transcode to
+	// transcode them to UTF-8, responding 415 for an unknown charset. The
+	// encoding/xml decoders transcode the charset of the prolog instead.
+	Charset string
+
+	// XMLHeader writes the xml.Header preamble before the encoding/xml
+	// responses; XMLRoot, if set, is the element they are wrapped in when
+	// they are slices, which encode as a list of elements, like jobs.
+	XMLHeader bool
+	XMLRoot   string
+
+	// Negotiate also generates for each func an http handler, like
+	// PutJobHandler, calling the one of the encoding the Accept header of
+	// the request prefers, by the Content-Type of its responses; it responds
+	// 406 Not Acceptable, listing the supported media types, if none is
+	// accepted. The body of the request is decoded with the encoding of its
+	// Content-Type, if any, and encoded with the one answering. DefaultEncoding is the import path of the encoding pkg
+	// answering */* and requests without Accept header; default is the first
+	// encoding of the func.
+	Negotiate       bool
+	DefaultEncoding string
+
+	// Problem makes the http handlers write RFC 7807 application/problem+json
+	// bodies, with the type, title, status and detail of the error, when they
+	// fail to read the request or when the func returns an error along a 4xx
+	// or 5xx status, instead of empty bodies or the error encoded.
+	Problem bool
+
+	// ErrorEncoder is a func of the parsed package, like WriteAPIError, the
+	// http handlers call with (w, r, status, err) where Problem writes its
+	// bodies, to write them: a func(http.ResponseWriter, *http.Request, int,
+	// error). It cannot go along Problem.
+	ErrorEncoder string
+
+	// ErrorHandler is a func of the parsed package, like
+	// HandleHttpErrorWithDefaultStatus, the http handlers call with (w, r,
+	// http.StatusBadRequest, err) when they cannot decode the request, like
+	// the ones varhandler generates, instead of responding the status.
+	ErrorHandler string
+
+	// Envelope wraps the responses of the http handlers in an Envelope
+	// struct, declared with them: {"data": resp, "meta": resp.Meta()} when
+	// the resp has a Meta() map[string]interface{} method, or
+	// {"error": {"status": 404, "title": "Not Found", "detail": err}} when
+	// they fail, unless Problem or ErrorEncoder is set. Streamed and copied
+	// responses are not wrapped.
+	Envelope bool
+
+	// JSONAPI renders the responses of the http handlers as JSON:API
+	// documents, application/vnd.api+json, declaring their types with them:
+	// {"data": {"type": "jobs", "id": "1", "attributes": resp}}, the type
+	// derived from the name of the one of the resp and the id from its ID
+	// field, if any, or {"errors": [{"status": "404", ...}]} when they fail,
+	// unless Problem or ErrorEncoder is set. It cannot go along Envelope.
+	JSONAPI bool
+
+	// HAL renders the responses of the http handlers as HAL resources,
+	// application/hal+json, declaring their types with them: the fields of
+	// the resp along with its _links, to itself and to the routes under its
+	// own, found among the GET routes of the funcs, like runs for
+	// /jobs/{id}/runs under /jobs/{id}, their wildcards filled with its
+	// fields, like ID. Slices are embedded in a collection. It cannot go
+	// along Envelope or JSONAPI.
+	HAL bool
+
+	// Pagination declares a Page struct, with the Limit and Offset of a
+	// list, and ParsePage, the http handlers read it with from the limit and
+	// offset, or page and per_page, query params of the requests, responding
+	// 400 Bad Request when they are out of bounds. The http handlers pass it
+	// to the funcs taking a Page parameter, and set the X-Total-Count header
+	// of the responses having a Total() int method, along with the Link one,
+	// built by PageLinks, to the first, previous, next and last pages when
+	// the func takes a Page. The parsed package may declare its own Page,
+	// with Limit and Offset int fields, to take it before generating.
+	Pagination bool
+
+	// PageSize is the Limit of the pages when the request does not tell it,
+	// and MaxPageSize the limit it can tell, with Pagination. Default is 20
+	// and 100.
+	PageSize, MaxPageSize int
+
+	Queue     string // consumer mode: nats (default) or kafka.
+	EnvPrefix string // job mode: prefix of the environment variables.
+
+	// Exclude are names or patterns of funcs not to generate for, like
+	// funcs matching a pattern or Eligible but not to expose.
+	Exclude []string
+
+	// File is the source file declaring the funcs to generate for, as
+	// Planned tells, to generate next to it: the funcs of the other files are
+	// left out. Default is every file.
+	File string
+
+	TemplateDir string   // Directory of templates overriding the embedded ones.
+	Hooks       []string // Hook executables.
+
+	// Adapters tell how to decode and encode with the encoding pkgs whose API
+	// differs from the one of encoding/json, by import path, overriding the
+	// built-in ones, like the one of gopkg.in/yaml.v3 closing its encoders.
+	Adapters map[string]Adapter
+
+	// ErrorStatuses are the statuses the http handlers respond, instead of
+	// the one the func returns, when it returns an error matching, by error:
+	// a sentinel var or a type, like ErrNotFound, *ValidationError or io.EOF,
+	// of the parsed package or of a package it imports, matched with
+	// errors.Is or errors.As in the order of their names.
+	ErrorStatuses map[string]int
+
+	// AllowMissing only logs a warning for the funcs that are not found,
+	// generating for the others, instead of failing.
+	AllowMissing bool
+
+	// Partial only generates for the funcs the parsed package declares,
+	// leaving out the other ones without a warning, like when generating for
+	// every package of a tree.
+	Partial bool
+
+	// Strict fails the generation on every warning instead of logging it,
+	// like a field a command cannot have a flag for, AllowMissing funcs or
+	// encoding pkgs without the funcs their Adapter calls.
+	Strict bool
+
+	// Verbose logs the files parsed, the funcs matched, the types resolved
+	// and the templates executed, to debug a generation.
+	Verbose bool
+
+	buf       bytes.Buffer                  // Accumulated output.
+	pkg       *loader.Package               // Package we are scanning.
+	files     []*parsedFile                 // Files of pkg.
+	decls     map[string]funcDecl           // Funcs and methods of pkg, by declKey.
+	funcs     []Func                        // Funcs to generate for.
+	encodings []encodingPkg                 // Encoding pkgs to generate for.
+	templates map[string]*template.Template // Parsed templates, by name.
+	imports   []string                      // Import paths used by the generated code.
+	names     map[string]pkgName            // How the generated code refers to imported pkgs, by path.
+	pkgPath   string                        // Import path of pkg, if the code is generated into another Package.
+	routes    []Route                       // Routes of the handlers generated.
+	handlers  []string                      // Names of the handlers generated, with Interface.
+	enveloped bool                          // Whether a handler generated uses the Envelope, or the JSON:API or HAL types.
+	halIndex  []halRoute                    // GET routes of the funcs, with HAL.
+	filters   []Filter                      // Filter parameters the http handlers read, by first use.
+	cursors   map[string]bool               // Funcs decoding the cursors declared, by name.
+	limiters  []Limiter                     // Limiters of the funcs rate limited, by first use.
+	breakers  []Breaker                     // Circuit breakers of the funcs, by first use.
+	replaying bool                          // An http handler replays its responses.
+	authed    bool                          // An http handler authenticates its requests.
+	jwtClaims []Claims                      // Claims parameters the http handlers read, by first use.
+	apiKeys   bool                          // An http handler checks the API key of its requests.
+	csrfToken bool                          // An http handler checks the CSRF token of its requests.
+	requiring bool                          // An http handler checks the requirements of its func.
+	err       error                         // First error met while generating.
+
+	// Name of the encoding pkg of the handler being generated, Fail encodes
+	// the Envelope errors with.
+	encodingPkg string
+
+	// Requests of the http handler being generated, as bound, for the
+	// Postman collection and the OpenAPI spec of its route.
+	request request
+
+	// Where the func being generated for is declared, like jober.go:10:6,
+	// and its signature, for the errors and warnings about it.
+	pos, signature string
+}
+
+// encodingPkg is an encoding pkg handlers are generated for.
+type encodingPkg struct {
+	path, name string
+	declared   map[string]bool // Names the pkg declares, for adapters.
+}
+
+// encodingPkgs caches the encoding pkgs imported, by path.
+var encodingPkgs struct {
+	sync.Mutex
+	m map[string]encodingPkg
+}
+
+// importEncoding checks that the encoding pkg at path exists.
+func importEncoding(path string) (encodingPkg, error) {
+	encodingPkgs.Lock()
+	defer encodingPkgs.Unlock()
+	if encoding, ok := encodingPkgs.m[path]; ok {
+		return encoding, nil
+	}
+	pkg, err := build.Import(path, ".", 0)
+	if err != nil {
+		return encodingPkg{}, fmt.Errorf("cannot use pkg %s: %s", path, err)
+	}
+	encoding := encodingPkg{path: path, name: pkg.Name, declared: declarations(pkg)}
+	if encodingPkgs.m == nil {
+		encodingPkgs.m = make(map[string]encodingPkg)
+	}
+	encodingPkgs.m[path] = encoding
+	return encoding, nil
+}
+
+// declarations returns the names of the funcs, types, consts and vars pkg
+// declares.
+func declarations(pkg *build.Package) map[string]bool {
+	declared := make(map[string]bool)
+	for _, name := range pkg.GoFiles {
+		file, err := parser.ParseFile(token.NewFileSet(), filepath.Join(pkg.Dir, name), nil, parser.SkipObjectResolution)
+		if err != nil {
+			continue
+		}
+		for _, decl := range file.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				if decl.Recv == nil {
+					declared[decl.Name.Name] = true
+				}
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					switch spec := spec.(type) {
+					case *ast.TypeSpec:
+						declared[spec.Name.Name] = true
+					case *ast.ValueSpec:
+						for _, name := range spec.Names {
+							declared[name.Name] = true
+						}
+					}
+				}
+			}
+		}
+	}
+	return declared
+}
+
+// Func is a func to generate for, with options overriding the Generator ones.
+type Func struct {
+	Name string `yaml:"name"`
+
+	// Encodings are the import paths of the encoding pkgs to generate for.
+	// Default is every encoding added to the Generator.
+	Encodings []string `yaml:"encodings"`
+
+	// Route is the http.ServeMux pattern the handler is registered on by
+	// RegisterHandlers, like "PUT /jobs"; with many encodings, only the
+	// handler of the first one is. Default is not to register it.
+	Route string `yaml:"route"`
+
+	// TypeArgs instantiate a generic func, like [Job] for Put[T any]; its
+	// handlers are named after them, like PutJobHandlerJSON.
+	TypeArgs []string `yaml:"type-args"`
+
+	// Params bind parameters of the func, by name, to where http handlers
+	// read them from: query, path or header, optionally followed by the name
+	// to read, like header:X-Trace-ID, or body to decode it. Default is the
+	// Source for parameters of a basic type, the body for the other one.
+	Params map[string]string `yaml:"params"`
+
+	WebSocket          bool   `yaml:"websocket"`
+	Stream             string `yaml:"stream"`
+	Source             string `yaml:"source"`
+	ContentType        string `yaml:"content-type"`
+	ContentDisposition string `yaml:"content-disposition"`
+
+	// RateLimit and RateLimitKey override the ones of the Generator; none
+	// lifts the limit.
+	RateLimit    string `yaml:"rate-limit"`
+	RateLimitKey string `yaml:"rate-limit-key"`
+
+	// Breaker overrides the one of the Generator; none lifts it.
+	Breaker string `yaml:"breaker"`
+
+	Idempotency bool `yaml:"idempotency"`
+
+	// APIKey overrides the one of the Generator; none lifts it.
+	APIKey string `yaml:"api-key"`
+
+	// Public skips the authentication of the requests with the Auth option.
+	Public bool `yaml:"public"`
+
+	// CSRF checks the CSRF token of the requests, see the CSRF option.
+	CSRF bool `yaml:"csrf"`
+
+	// Requires tells what the func requires of the principal of the
+	// requests, by key, like role: admin,owner; see Requires.
+	Requires map[string]string `yaml:"requires"`
+}
+
+func (g *Generator) Printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// logf logs what is done with Verbose.
+func (g *Generator) logf(format string, args ...interface{}) {
+	if g.Verbose {
+		log.Printf(format, args...)
+	}
+}
+
+// warnf logs a warning, or records it as an error with Strict.
+func (g *Generator) warnf(format string, args ...interface{}) {
+	if g.Strict {
+		g.errorf(format, args...)
+		return
+	}
+	log.Printf("warning: %s", g.at(fmt.Sprintf(format, args...)))
+}
+
+// errorf records an error stopping the generation; only the first one is kept.
+func (g *Generator) errorf(format string, args ...interface{}) {
+	if g.err == nil {
+		g.err = errors.New(g.at(fmt.Sprintf(format, args...)))
+	}
+}
+
+// at returns msg prefixed by where the func being generated for is
+// declared and followed by its signature, if any.
+func (g *Generator) at(msg string) string {
+	if g.pos == "" {
+		return msg
+	}
+	return fmt.Sprintf("%s: %s\n\t%s", g.pos, msg, g.signature)
+}
+
+// addImport records that the generated code uses the pkg at path, once
+// however many funcs or encodings use it: printImports prints them in one
+// import block.
+func (g *Generator) addImport(path string) {
+	for _, imported := range g.imports {
+		if imported == path {
+			return
+		}
+	}
+	g.imports = append(g.imports, path)
+}
+
+// AddFunc adds funcs to generate for. A name can also be a glob like Put*
+// or a regular expression like Handle.*, matched against the Eligible funcs,
+// or name a method, like (*Server).PutJob, setting the Receiver.
+func (g *Generator) AddFunc(names ...string) {
+	for _, name := range names {
+		g.funcs = append(g.funcs, Func{Name: name})
+	}
+}
+
+// Add adds funcs to generate for, with their own options.
+// It fails if one of their encoding pkgs does not exist.
+func (g *Generator) Add(funcs ...Func) error {
+	for _, fn := range funcs {
+		for _, path := range fn.Encodings {
+			if _, err := importEncoding(path); err != nil {
+				return err
+			}
+		}
+		g.funcs = append(g.funcs, fn)
+	}
+	return nil
+}
+
+// AddEncoding adds encoding pkgs to generate for, like encoding/json.
+// It fails if a pkg does not exist.
+func (g *Generator) AddEncoding(paths ...string) error {
+	for _, path := range paths {
+		encoding, err := importEncoding(path)
+		if err != nil {
+			return err
+		}
+		g.encodings = append(g.encodings, encoding)
+	}
+	return nil
+}
+
+// SetTemplate replaces the name template, like handler, by the one in file.
+func (g *Generator) SetTemplate(name, file string) error {
+	_, err := g.parseTemplate(name, file)
+	return err
+}
+
+// Parse parses the package of the funcs: either one directory or a list of
+// files of a single package. Default is the current directory.
+func (g *Generator) Parse(args ...string) error {
+	if len(args) == 0 {
+		args = []string{"."}
+	}
+	var funcs, encodings []string
+	for _, fn := range g.funcs {
+		funcs = append(funcs, fn.Name)
+	}
+	for _, encoding := range g.encodings {
+		encodings = append(encodings, encoding.path)
+	}
+	_, err := g.runHooks(HookRequest{
+		Stage:     "pre-parse",
+		Files:     args,
+		Funcs:     funcs,
+		Encodings: encodings,
+	})
+	if err != nil {
+		return err
+	}
+	var pkg *loader.Package
+	switch g.Tests {
+	case "":
+		pkg, err = loader.Load(args...)
+	case "test", "xtest":
+		if len(args) != 1 || !utils.IsDirectory(args[0]) {
+			return errors.New("cannot parse the test files of a list of files: give the directory of the package")
+		}
+		pkg, err = loader.LoadTestDir(args[0], g.Tests == "xtest")
+	default:
+		return fmt.Errorf("unknown tests: %s", g.Tests)
+	}
+	if err != nil {
+		return err
+	}
+	g.pkg = pkg
+	g.files = nil
+	g.decls = make(map[string]funcDecl)
+	for _, file := range pkg.Files {
+		g.logf("parsed %s", pkg.Fset.Position(file.Package).Filename)
+		f := &parsedFile{
+			file: file,
+			pkg:  pkg,
+		}
+		g.files = append(g.files, f)
+		f.index(g.decls)
+	}
+	return nil
+}
+
+// Render generates the code for every func and encoding added, and writes it
+// formatted to w.
+func (g *Generator) Render(w io.Writer) error {
+	return g.render(w, output{routes: "routes"})
+}
+
+// RenderEncoding is like Render, but only writes the code of the encoding
+// pkg at path, with the imports it needs. The file is built unless the
+// no<name> build tag is set, like nojson for encoding/json.
+func (g *Generator) RenderEncoding(w io.Writer, path string) error {
+	name, err := g.EncodingAlias(path)
+	if err != nil {
+		return err
+	}
+	if g.Interface != "" {
+		return fmt.Errorf("cannot split the handlers of interface %s", g.Interface)
+	}
+	if g.Negotiate {
+		return errors.New("cannot split the handlers negotiating the encoding")
+	}
+	if g.Envelope || g.JSONAPI || g.HAL {
+		return errors.New("cannot split the handlers sharing the types of their documents")
+	}
+	if g.Pagination {
+		return errors.New("cannot split the handlers sharing the Page type")
+	}
+	if g.Sessions {
+		return errors.New("cannot split the handlers sharing the Session type")
+	}
+	if g.Mode == "health" {
+		return errors.New("cannot split the health handlers sharing their checks")
+	}
+	if g.Log != "" {
+		return errors.New("cannot split the handlers sharing their logger")
+	}
+	if g.Otel {
+		return errors.New("cannot split the handlers sharing their tracer")
+	}
+	if g.Metrics != "" {
+		return errors.New("cannot split the handlers sharing their metrics")
+	}
+	return g.render(w, output{only: path, tag: "no" + name, routes: "routes_encoding"})
+}
+
+// RenderRoutes is like Render, but only writes RegisterHandlers, registering
+// the routes of the RenderEncoding files that are built.
+func (g *Generator) RenderRoutes(w io.Writer) error {
+	return g.render(w, output{none: true, routes: "routes_register"})
+}
+
+// EncodingName returns the name of the encoding pkg at path, like json for
+// encoding/json.
+func EncodingName(path string) (string, error) {
+	encoding, err := importEncoding(path)
+	return encoding.name, err
+}
+
+// output selects what render writes.
+type output struct {
+	only   string // Encoding pkg to write the code of; default is every one.
+	none   bool   // Write the code of no encoding pkg.
+	tag    string // Build tag excluding the file, if any.
+	routes string // Template executed with the routes of the code written.
+}
+
+// keep reports whether the code of the encoding pkg at path is written.
+func (o output) keep(path string) bool {
+	return !o.none && (o.only == "" || o.only == path)
+}
+
+// render generates the code selected by o, then writes it formatted to w.
+func (g *Generator) render(w io.Writer, o output) error {
+	if g.pkg == nil {
+		return errors.New("no package parsed")
+	}
+	switch g.Stream {
+	case "", "ndjson":
+	default:
+		return fmt.Errorf("unknown stream format: %s", g.Stream)
+	}
+	switch g.Source {
+	case "", "body", "query", "path", "header":
+	default:
+		return fmt.Errorf("unknown source: %s", g.Source)
+	}
+	switch g.Queue {
+	case "", "nats", "kafka":
+	default:
+		return fmt.Errorf("unknown queue: %s", g.Queue)
+	}
+	switch g.Mode {
+	case "", "consumer", "command", "job", "health":
+	default:
+		return fmt.Errorf("unknown mode: %s", g.Mode)
+	}
+	if g.BuildConstraint != "" {
+		if _, err := constraint.Parse("//go:build " + g.BuildConstraint); err != nil {
+			return fmt.Errorf("invalid build constraint %s: %s", g.BuildConstraint, err)
+		}
+	}
+	switch g.Charset {
+	case "", "reject", "transcode":
+	default:
+		return fmt.Errorf("unknown charset handling: %s", g.Charset)
+	}
+	if g.XMLRoot != "" && !xmlName.MatchString(g.XMLRoot) {
+		return fmt.Errorf("invalid xml root element name: %q", g.XMLRoot)
+	}
+	if g.Negotiate && g.Mode != "" {
+		return fmt.Errorf("cannot negotiate the encoding of %ss", g.Mode)
+	}
+	if g.Envelope && g.Mode != "" {
+		return fmt.Errorf("cannot wrap the responses of %ss in an envelope", g.Mode)
+	}
+	if g.JSONAPI && g.Mode != "" {
+		return fmt.Errorf("cannot render the responses of %ss as JSON:API documents", g.Mode)
+	}
+	if g.JSONAPI && g.Envelope {
+		return errors.New("cannot render JSON:API documents in an envelope")
+	}
+	if g.HAL && g.Mode != "" {
+		return fmt.Errorf("cannot render the responses of %ss as HAL resources", g.Mode)
+	}
+	if g.HAL && (g.Envelope || g.JSONAPI) {
+		return errors.New("cannot render HAL resources in another document")
+	}
+	if g.Negotiate && g.Interface != "" {
+		return fmt.Errorf("cannot negotiate the encoding of the handlers of interface %s", g.Interface)
+	}
+	switch g.Log {
+	case "", "slog":
+	default:
+		return fmt.Errorf("unknown logging: %s", g.Log)
+	}
+	if g.Log != "" && g.Mode != "" {
+		return fmt.Errorf("cannot log the requests of %ss", g.Mode)
+	}
+	switch g.Metrics {
+	case "", "prometheus":
+	default:
+		return fmt.Errorf("unknown metrics: %s", g.Metrics)
+	}
+	if g.Metrics != "" && g.Mode != "" {
+		return fmt.Errorf("cannot measure the requests of %ss", g.Mode)
+	}
+	if g.PprofLabels && g.Mode != "" {
+		return fmt.Errorf("cannot label the profiles of %ss", g.Mode)
+	}
+	if g.Otel && g.Mode != "" {
+		return fmt.Errorf("cannot trace the requests of %ss", g.Mode)
+	}
+	if g.PageSize < 0 || g.MaxPageSize < 0 {
+		return errors.New("invalid negative page size")
+	}
+	if g.RateLimit != "" && g.RateLimit != "none" {
+		if _, _, err := parseRateLimit(g.RateLimit); err != nil {
+			return err
+		}
+		if g.Mode != "" {
+			return fmt.Errorf("cannot rate limit the requests of %ss", g.Mode)
+		}
+	}
+	if _, err := rateLimitKey(g.RateLimitKey); err != nil {
+		return err
+	}
+	if g.JWT && g.Mode != "" {
+		return fmt.Errorf("cannot read the claims of %ss", g.Mode)
+	}
+	if g.APIKey != "" && g.APIKey != "none" {
+		if _, err := apiKeySource(g.APIKey); err != nil {
+			return err
+		}
+		if g.Mode != "" {
+			return fmt.Errorf("cannot check the API keys of %ss", g.Mode)
+		}
+	}
+	if g.Auth && g.Mode != "" {
+		return fmt.Errorf("cannot authenticate the requests of %ss", g.Mode)
+	}
+	if g.CSRF && g.Mode != "" {
+		return fmt.Errorf("cannot check the CSRF tokens of %ss", g.Mode)
+	}
+	if g.Idempotency && g.Mode != "" {
+		return fmt.Errorf("cannot replay the responses of %ss", g.Mode)
+	}
+	if g.Breaker != "" && g.Breaker != "none" {
+		if _, _, err := parseBreaker(g.Breaker); err != nil {
+			return err
+		}
+		if g.Mode != "" {
+			return fmt.Errorf("cannot break the calls of %ss", g.Mode)
+		}
+	}
+
+	g.buf.Reset()
+	g.imports = nil
+	g.nameEncodings()
+	g.pkgPath = ""
+	g.routes = nil
+	g.handlers = nil
+	g.enveloped = false
+	g.halIndex = nil
+	g.filters = nil
+	g.cursors = nil
+	g.limiters = nil
+	g.breakers = nil
+	g.replaying = false
+	g.authed = false
+	g.jwtClaims = nil
+	g.apiKeys = false
+	g.requiring = false
+	g.csrfToken = false
+	g.err = nil
+	if err := g.methodFuncs(); err != nil {
+		return err
+	}
+	if g.Receiver != "" {
+		if g.Package != "" {
+			return errors.New("cannot generate methods into another package")
+		}
+		if _, ok := g.pkg.Types.Scope().Lookup(g.Receiver).(*types.TypeName); !ok {
+			return fmt.Errorf("receiver type %s not found", g.Receiver)
+		}
+	}
+	if g.Interface != "" {
+		if err := g.checkInterface(); err != nil {
+			return err
+		}
+	}
+	if g.ErrorEncoder != "" && g.Problem {
+		return fmt.Errorf("cannot write problem responses and call error encoder %s", g.ErrorEncoder)
+	}
+	if err := g.checkErrorFunc("error encoder", g.ErrorEncoder); err != nil {
+		return err
+	}
+	if err := g.checkErrorFunc("error handler", g.ErrorHandler); err != nil {
+		return err
+	}
+	if err := g.checkErrorStatuses(); err != nil {
+		return err
+	}
+	if g.Pagination {
+		if err := g.checkPagination(); err != nil {
+			return err
+		}
+	}
+	if g.Sessions {
+		if err := g.checkSessions(); err != nil {
+			return err
+		}
+	}
+	if g.Package != "" {
+		if g.Package == g.pkg.Name {
+			return fmt.Errorf("cannot generate into package %s: it is the one of the funcs", g.Package)
+		}
+		path, err := g.pkg.ImportPath()
+		if err != nil {
+			return err
+		}
+		g.addImport(path)
+		g.pkgPath = path
+		g.importName(path, g.pkg.Name)
+	}
+
+	if g.Mode == "health" {
+		if err := g.buildHealth(); err != nil {
+			return err
+		}
+	}
+	funcs, err := g.expandFuncs()
+	if err != nil {
+		return err
+	}
+	if g.HAL {
+		g.halIndex = g.halRoutes(funcs)
+	}
+
+	// Run generate for each type.
+	warned := make(map[string]bool) // Encoding pkgs warned about.
+	for _, fn := range funcs {
+		encodings, err := g.funcEncodings(fn)
+		if err != nil {
+			return err
+		}
+		if len(fn.TypeArgs) > 0 && (g.Mode == "command" || g.Mode == "job") {
+			return fmt.Errorf("cannot generate %ss for an instantiation of %s", g.Mode, fn.Name)
+		}
+		route := fn.Route
+		if g.Negotiate {
+			fn.Route = "" // Registered with the handler negotiating.
+		}
+		var funcName string
+		var negotiated []Negotiated
+		for _, encoding := range encodings {
+			if missing := g.missing(encoding); len(missing) > 0 && !warned[encoding.path] {
+				warned[encoding.path] = true
+				g.warnf("%s does not declare %s: the generated code may not compile; set an adapter for it", encoding.path, strings.Join(missing, ", "))
+			}
+			start, imports, routes := g.buf.Len(), g.imports, len(g.routes)
+			g.addImport(encoding.path)
+			pkgName := g.importName(encoding.path, encoding.name)
+			name, handler := g.generate(fn, pkgName)
+			if handler != "" {
+				funcName = name
+				negotiated = append(negotiated, Negotiated{
+					ContentType: g.contentType(encoding.path),
+					Handler:     handler,
+					encoding:    encoding.path,
+					EncodingPkg: pkgName,
+				})
+			}
+			for i := routes; i < len(g.routes); i++ {
+				g.routes[i].encoding = encoding.path
+			}
+			if !o.keep(encoding.path) {
+				// Drop what was generated, but keep the route.
+				g.buf.Truncate(start)
+				g.imports = imports
+			}
+			fn.Route = "" // Registered once.
+		}
+		if g.Negotiate && len(negotiated) > 0 {
+			g.buildNegotiation(funcName, route, negotiated)
+		}
+	}
+	var routes []Route
+	for _, route := range g.routes {
+		if o.none || o.keep(route.encoding) {
+			routes = append(routes, route)
+		}
+	}
+	receiver := g.receiver()
+	if g.Interface != "" {
+		handlers, impl := g.interfaceNames()
+		g.execute("interface", Interface{
+			Name:      handlers,
+			New:       g.exported("New" + g.Interface + "Handlers"),
+			Impl:      impl,
+			Interface: g.typeQual() + g.Interface,
+			Handlers:  g.handlers,
+		})
+		receiver = "*" + handlers // Handlers are fields of it too.
+	}
+	if g.enveloped && g.JSONAPI {
+		g.execute("jsonapi", g.jsonapiNames())
+	} else if g.enveloped && g.HAL {
+		g.addImport("encoding/json")
+		g.execute("hal", g.halNames())
+	} else if g.enveloped {
+		g.execute("envelope", struct{ Envelope, Error string }{g.exported("Envelope"), g.exported("EnvelopeError")})
+	}
+	if g.Pagination {
+		g.addImport("fmt")
+		g.addImport("math")
+		g.addImport("strconv")
+		g.addImport("strings")
+		g.execute("page", g.pagination())
+	}
+	if g.Sessions {
+		g.addImport("context")
+		g.addImport("errors")
+		g.execute("sessions", g.sessionNames())
+	}
+	if len(g.filters) > 0 && o.only != "" {
+		return fmt.Errorf("cannot split the handlers sharing %s", g.filters[0].Name)
+	}
+	for _, f := range g.filters {
+		g.execute("filter", f)
+	}
+	if len(g.limiters) > 0 && o.only != "" {
+		return errors.New("cannot split the handlers sharing their rate limiters")
+	}
+	if len(g.limiters) > 0 {
+		g.execute("ratelimit_limiters", g.rateLimit())
+	}
+	if len(g.breakers) > 0 && o.only != "" {
+		return errors.New("cannot split the handlers sharing their circuit breakers")
+	}
+	if len(g.breakers) > 0 {
+		g.addImport("math")
+		g.addImport("strconv")
+		g.addImport("sync")
+		g.addImport("time")
+		g.execute("breakers", g.breakers)
+	}
+	if len(g.jwtClaims) > 0 && o.only != "" {
+		return fmt.Errorf("cannot split the handlers sharing %s", g.jwtClaims[0].Name)
+	}
+	if g.csrfToken && o.only != "" {
+		return errors.New("cannot split the handlers sharing their CSRF tokens")
+	}
+	if g.csrfToken {
+		g.addImport("crypto/rand")
+		g.addImport("crypto/subtle")
+		g.addImport("encoding/base64")
+		g.addImport("errors")
+		g.execute("csrf_tokens", g.csrfNames())
+	}
+	if g.requiring && o.only != "" {
+		return errors.New("cannot split the handlers sharing their Authorizer")
+	}
+	if g.requiring {
+		g.addImport("context")
+		g.addImport("errors")
+		g.addImport("fmt")
+		g.addImport("strings")
+		g.execute("authorizer", g.authorizerNames())
+	}
+	if g.apiKeys && o.only != "" {
+		return errors.New("cannot split the handlers sharing their APIKeyValidator")
+	}
+	if g.apiKeys {
+		g.addImport("context")
+		g.addImport("errors")
+		g.execute("apikey_validator", g.apiKeyNames())
+	}
+	if g.authed && o.only != "" {
+		return errors.New("cannot split the handlers sharing their Authenticator")
+	}
+	if g.authed {
+		g.addImport("context")
+		g.addImport("errors")
+		g.execute("auth_authenticator", g.authNames())
+	}
+	if g.replaying && o.only != "" {
+		return errors.New("cannot split the handlers sharing their idempotency store")
+	}
+	if g.replaying {
+		g.addImport("bytes")
+		g.addImport("context")
+		g.addImport("sync")
+		g.addImport("time")
+		g.execute("idempotency_store", g.idempotencyNames())
+	}
+	if len(routes) > 0 {
+		r := Routes{
+			Register: g.exported("RegisterHandlers"),
+			Receiver: receiver,
+			Routes:   routes,
+		}
+		if g.RequestID {
+			r.Middleware = g.exported("WithRequestID")
+		}
+		if g.Manifest {
+			r.Manifest = g.manifestNames()
+			r.Manifest.Split = o.routes != "routes"
+		}
+		if g.OpenAPI != "" && g.Mode == "" && len(routes) > 0 && o.routes != "routes_encoding" {
+			r.Docs = g.exported("RegisterDocs")
+		}
+		g.execute(o.routes, r)
+		if r.Docs != "" {
+			spec, err := g.openAPI(routes)
+			if err != nil {
+				return err
+			}
+			g.addImport("io")
+			g.execute("openapi", g.docsNames(spec))
+		}
+		if r.Manifest != nil && o.routes != "routes_encoding" {
+			g.execute("manifest", r)
+		}
+		if g.URLs && o.routes != "routes_encoding" {
+			g.execute("urls", g.urlBuilders(routes))
+		}
+		if g.Consts && len(routes) > 0 && o.routes != "routes_encoding" {
+			g.execute("consts", g.routeConsts(routes))
+		}
+	}
+	if g.Log != "" {
+		g.execute("slog_logger", g.slogNames())
+	}
+	if g.Metrics != "" {
+		g.addImport("github.com/prometheus/client_golang/prometheus")
+		g.addImport("github.com/prometheus/client_golang/prometheus/promauto")
+		g.execute("prometheus_metrics", Metrics{Register: g.exported("RegisterMetrics")})
+	}
+	if g.Log != "" || g.Metrics != "" {
+		g.execute("status_writer", nil)
+	}
+	if g.Otel {
+		g.addImport("go.opentelemetry.io/otel")
+		g.execute("otel_tracer", g.pkg.Name)
+	}
+	if g.RequestID && o.only == "" {
+		g.addImport("context")
+		g.addImport("crypto/rand")
+		g.addImport("encoding/hex")
+		g.execute("requestid", struct{ With, Get string }{g.exported("WithRequestID"), g.exported("RequestID")})
+	}
+	if g.err != nil {
+		return g.err
+	}
+
+	// Print the header, package clause and imports before the handlers,
+	// now that we know what the handlers need.
+	handlers := g.buf.String()
+	g.buf.Reset()
+	g.printGenerated()
+	if expr := g.buildConstraint(o.tag); expr != nil {
+		g.Printf("//go:build %s\n", expr)
+		g.Printf("\n")
+	}
+	pkgName := g.pkg.Name
+	if g.Package != "" {
+		pkgName = g.Package
+	}
+	g.Printf("package %s\n", pkgName)
+	g.Printf("\n")
+	g.printImports()
+	g.buf.WriteString(handlers)
+
+	// Format the output.
+	src := g.format()
+	if g.err != nil {
+		return g.err
+	}
+
+	src, err = g.postGenerate(g.Output, src)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+// printGenerated prints the Header, then the "Code generated" line crediting
+// By, each followed by a blank line.
+func (g *Generator) printGenerated() {
+	by := g.By
+	if by == "" {
+		by = "handlergen"
+	}
+	g.printHeader()
+	version := ""
+	if g.Version != "" {
+		version = " (" + g.Version + ")"
+	}
+	g.Printf("// Code generated by \"%s\"%s; DO NOT EDIT\n", by, version)
+	g.Printf("\n")
+}
+
+// printHeader prints the Header, commented, followed by a blank line.
+func (g *Generator) printHeader() {
+	header := strings.TrimRight(g.Header, "\n")
+	if strings.TrimSpace(header) == "" {
+		return
+	}
+	if strings.HasPrefix(header, "//") || strings.HasPrefix(header, "/*") {
+		g.Printf("%s\n", header)
+	} else {
+		for _, line := range strings.Split(header, "\n") {
+			g.Printf("%s\n", strings.TrimRight("// "+line, " "))
+		}
+	}
+	g.Printf("\n")
+}
+
+// buildConstraint returns the build constraint of a generated file, excluded
+// by the tag if set, if any.
+func (g *Generator) buildConstraint(tag string) constraint.Expr {
+	var expr constraint.Expr
+	if g.BuildConstraint != "" {
+		expr, _ = constraint.Parse("//go:build " + g.BuildConstraint)
+	}
+	if tag == "" {
+		return expr
+	}
+	not := &constraint.NotExpr{X: &constraint.TagExpr{Tag: tag}}
+	if expr == nil {
+		return not
+	}
+	return &constraint.AndExpr{X: expr, Y: not}
+}
+
+// printImports prints the imports of the generated code in one block,
+// sorted, the standard pkgs first, with the aliases of the pkgs referred to
+// by another name than theirs.
+func (g *Generator) printImports() {
+	switch len(g.imports) {
+	case 0:
+		return
+	case 1:
+		g.Printf("import %s\n", g.importSpec(g.imports[0]))
+		return
+	}
+	var std, other []string
+	for _, path := range g.imports {
+		if standard(path) {
+			std = append(std, path)
+		} else {
+			other = append(other, path)
+		}
+	}
+	sort.Strings(std)
+	sort.Strings(other)
+	g.Printf("import (\n")
+	for _, path := range std {
+		g.Printf("\t%s\n", g.importSpec(path))
+	}
+	if len(std) > 0 && len(other) > 0 {
+		g.Printf("\n")
+	}
+	for _, path := range other {
+		g.Printf("\t%s\n", g.importSpec(path))
+	}
+	g.Printf(")\n")
+}
+
+// importSpec returns how the pkg at path is imported, like "encoding/json"
+// or goccyjson "github.com/goccy/go-json" if aliased.
+func (g *Generator) importSpec(path string) string {
+	if alias := g.names[path].alias; alias != "" {
+		return fmt.Sprintf("%s %q", alias, path)
+	}
+	return fmt.Sprintf("%q", path)
+}
+
+// parsedFile holds a single parsed file and associated data.
+type parsedFile struct {
+	pkg  *loader.Package // Package to which this file belongs.
+	file *ast.File       // Parsed AST.
+	// These fields are reset for each type being generated.
+	funcName, encodingPkgName string // Name of the type.
+	typeArgs                  []string
+	signature
+	found bool
+}
+
+// signature is what lookup finds of a func.
+type signature struct {
+	params []param
+	targs  []types.Type // instantiating the func, if generic
+	result resultKind
+	// The func returns (int, resp) rather than (resp, int).
+	statusFirst bool
+	header      bool   // The func also returns an http.Header.
+	headers     bool   // The response has a Headers() http.Header method.
+	cookie      bool   // The func also returns a []*http.Cookie, last.
+	cookies     bool   // The response has a Cookies() []*http.Cookie method.
+	meta        bool   // The response has a Meta() map[string]interface{} method.
+	total       bool   // The response has a Total() int method.
+	redirect    string // Location of a response with a 3xx status, like resp.
+	results     *types.Tuple
+}
+
+// param is a parameter of a func.
+type param struct {
+	name     string     // if any
+	t        types.Type // type of the parameter
+	fullname string     // type of the parameter, as written in the generated code
+}
+
+// resultKind is the shape of the response returned by a func.
+type resultKind int
+
+const (
+	plainResult  resultKind = iota
+	chanResult              // <-chan T
+	sliceResult             // []T
+	readerResult            // implements io.Reader
+	bytesResult             // []byte
+)
+
+// funcEncodings returns the encoding pkgs to generate fn for, checking its
+// options on the way.
+func (g *Generator) funcEncodings(fn Func) ([]encodingPkg, error) {
+	switch fn.Stream {
+	case "", "ndjson":
+	default:
+		return nil, fmt.Errorf("%s: unknown stream format: %s", fn.Name, fn.Stream)
+	}
+	switch fn.Source {
+	case "", "body", "query", "path", "header":
+	default:
+		return nil, fmt.Errorf("%s: unknown source: %s", fn.Name, fn.Source)
+	}
+	if len(fn.Encodings) == 0 {
+		return g.encodings, nil
+	}
+	var encodings []encodingPkg
+	for _, path := range fn.Encodings {
+		encoding, err := importEncoding(path)
+		if err != nil {
+			return nil, err
+		}
+		encodings = append(encodings, encoding)
+	}
+	return encodings, nil
+}
+
+// lookup finds the declaration of the fn func, returning its parameter, the
+// types instantiating it if generic and the shape of its result.
+func (g *Generator) lookup(fn Func) (sig signature, found bool) {
+	d, ok := g.decls[declKey(g.Receiver+g.Interface, fn.Name)]
+	if !ok {
+		return sig, false
+	}
+	// Set the state for this run of the walker.
+	file := d.file
+	file.funcName = fn.Name
+	file.typeArgs = fn.TypeArgs
+	file.signature = signature{}
+	file.found = false
+	file.parseFunc(d.name, d.ft)
+	if !file.found {
+		return sig, false
+	}
+	sig = file.signature
+	var params []string
+	for i, p := range sig.params {
+		if p.fullname == "" {
+			sig.params[i].fullname = types.TypeString(p.t, g.qualifier)
+		}
+		params = append(params, sig.params[i].fullname)
+	}
+	g.logf("%s%s: parameters (%s), results %s", fn.Name, typeList(sig.targs, g.qualifier), strings.Join(params, ", "), sig.results)
+	return sig, true
+}
+
+// generate produces the Http handler method for the func and encoding,
+// returning the names build returns.
+func (g *Generator) generate(fn Func, encodingPkgName string) (funcName, handler string) {
+	g.pos, g.signature = g.declaration(fn.Name)
+	defer func() { g.pos, g.signature = "", "" }()
+	sig, found := g.lookup(fn)
+	switch {
+	case found:
+		return g.build(fn, encodingPkgName, sig)
+	case g.AllowMissing && !g.Strict:
+		log.Printf("warning: %s", g.at(fmt.Sprintf("func %s not found, skipped", fn.Name)))
+	default:
+		g.errorf("func %s not found", fn.Name)
+	}
+	return "", ""
+}
+
+// format returns the goimports-ed contents of the Generator's buffer.
+func (g *Generator) format() []byte {
+	filename := g.Output
+	if filename == "-" {
+		filename = ""
+	}
+	// Like goimports, also add the imports the templates did not tell, like
+	// net/http, and remove the unused ones.
+	src, err := imports.Process(filename, g.buf.Bytes(), nil)
+	if err != nil {
+		// Should never happen, but can arise when developing this code.
+		// The user can compile the output to see the error.
+		g.warnf("internal error: invalid Go generated: %s", err)
+		if !g.Strict {
+			log.Printf("warning: compile the package to analyze the error")
+		}
+		return g.buf.Bytes()
+	}
+	if err := checkImports(src); err != nil {
+		g.warnf("internal error: %s", err)
+	}
+	return src
+}
+
+// checkImports checks that src imports its pkgs in one block, each once.
+func checkImports(src []byte) error {
+	file, err := parser.ParseFile(token.NewFileSet(), "", src, parser.ImportsOnly)
+	if err != nil {
+		return err
+	}
+	blocks := 0
+	for _, decl := range file.Decls {
+		if decl, ok := decl.(*ast.GenDecl); ok && decl.Tok == token.IMPORT {
+			blocks++
+		}
+	}
+	if blocks > 1 {
+		return fmt.Errorf("%d import declarations generated", blocks)
+	}
+	seen := make(map[string]bool)
+	for _, spec := range file.Imports {
+		if seen[spec.Path.Value] {
+			return fmt.Errorf("%s imported twice", spec.Path.Value)
+		}
+		seen[spec.Path.Value] = true
+	}
+	return nil
+}
+
+// funcDecl is the declaration of a func or method.
+type funcDecl struct {
+	file *parsedFile
+	name *ast.Ident
+	ft   *ast.FuncType
+	doc  *ast.CommentGroup
+}
+
+// declKey returns the key of the func named name in Generator.decls: the
+// name, prefixed by the type it is a method of, like Server.PutJob, if any.
+func declKey(recv, name string) string {
+	if recv == "" {
+		return name
+	}
+	return recv + "." + name
+}
+
+// index adds the funcs declared by the file to decls, along with the methods
+// of the types and interfaces it declares.
+func (f *parsedFile) index(decls map[string]funcDecl) {
+	for _, decl := range f.file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			decls[declKey(recvName(decl), decl.Name.Name)] = funcDecl{f, decl.Name, decl.Type, decl.Doc}
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				spec, ok := spec.(*ast.TypeSpec)
+				if !ok {
+					continue
+				}
+				if t, ok := spec.Type.(*ast.InterfaceType); ok {
+					names, funcs, docs := interfaceMethods(t)
+					for i, name := range names {
+						decls[declKey(spec.Name.Name, name.Name)] = funcDecl{f, name, funcs[i], docs[i]}
+					}
+				}
+			}
+		}
+	}
+}
+
+// position returns where node is, like jober.go:10:6.
+func (f *parsedFile) position(node ast.Node) string {
+	return f.pkg.Fset.Position(node.Pos()).String()
+}
+
+// parseFunc records the parameter types and result shape of the func named
+// name of type ft.
+func (f *parsedFile) parseFunc(name *ast.Ident, ft *ast.FuncType) {
+	fn, ok := f.pkg.Defs[name].(*types.Func)
+	if !ok {
+		log.Printf("%s: %s: no type information", f.position(name), f.funcName)
+		return
+	}
+	sig := fn.Type().(*types.Signature)
+	switch {
+	case ft.TypeParams != nil && len(f.typeArgs) == 0:
+		log.Printf("%s: %s has type parameters: instantiate it, like -instantiate '%s[T]'", f.position(name), f.funcName, f.funcName)
+		return
+	case ft.TypeParams == nil && len(f.typeArgs) > 0:
+		log.Printf("%s: %s has no type parameters to instantiate", f.position(name), f.funcName)
+		return
+	case ft.TypeParams != nil:
+		var err error
+		sig, f.targs, err = instantiate(f.pkg, fn, f.typeArgs)
+		if err != nil {
+			log.Printf("%s: %s", f.position(name), err)
+			return
+		}
+	}
+
+	f.params = nil
+	for _, field := range ft.Params.List {
+		fullname, ok := typeName(field.Type)
+		if !ok {
+			log.Printf("%s: %s: parameter type not expected: %s", f.position(field.Type), f.funcName, types.ExprString(field.Type))
+			return
+		}
+		names := field.Names
+		if len(names) == 0 {
+			names = []*ast.Ident{nil} // Unnamed parameter.
+		}
+		for _, n := range names {
+			p := param{
+				t:        sig.Params().At(len(f.params)).Type(),
+				fullname: fullname,
+			}
+			if n != nil && n.Name != "_" {
+				p.name = n.Name
+			}
+			if ft.TypeParams != nil {
+				// The parameter can hold type parameters, written by lookup.
+				p.fullname = ""
+				if ptr, ok := p.t.(*types.Pointer); ok {
+					if _, ok := ptr.Elem().(*types.Pointer); ok {
+						log.Printf("%s: %s: type not expected: %s", f.position(field.Type), f.funcName, types.TypeString(p.t, types.RelativeTo(f.pkg.Types)))
+						return
+					}
+				}
+			}
+			f.params = append(f.params, p)
+		}
+	}
+	f.result = plainResult
+	f.results = sig.Results()
+	f.statusFirst = statusFirst(f.results)
+	_, f.header, f.cookie = extraResults(f.results)
+	if sig.Results().Len() > 0 {
+		t := sig.Results().At(0).Type()
+		if f.statusFirst {
+			t = sig.Results().At(1).Type()
+		}
+		switch r := t.(type) {
+		case *types.Chan:
+			if r.Dir() == types.RecvOnly {
+				f.result = chanResult
+			}
+		case *types.Slice:
+			f.result = sliceResult
+		}
+		if types.Identical(t, types.NewSlice(types.Typ[types.Byte])) {
+			f.result = bytesResult
+		} else if isReader(t) {
+			f.result = readerResult
+		}
+		f.headers = hasMethod(t, "Headers", isHeader)
+		f.cookies = hasMethod(t, "Cookies", isCookies)
+		f.meta = hasMethod(t, "Meta", isMeta)
+		f.total = hasMethod(t, "Total", isInt)
+		f.redirect = redirect(t)
+	}
+	f.found = true
+}
+
+// typeName returns the type expr as written in the generated code, or ""
+// when lookup writes it from its type.
+func typeName(expr ast.Expr) (string, bool) {
+	switch v := expr.(type) { // get var type
+	case *ast.Ident:
+		// plain type like from type x struct {}
+		return v.Name, true
+	case *ast.SelectorExpr:
+		// import type like pkgname.X
+		return fmt.Sprintf("%s.%s", v.X, v.Sel), true
+	case *ast.IndexExpr, *ast.IndexListExpr, *ast.ArrayType, *ast.MapType:
+		// instantiated generic type like Page[Job], slice like []Job or map
+		// like map[string]Job
+		return "", true
+	case *ast.StarExpr:
+		// pointer like *X, *pkgname.X or *Page[Job]
+		switch x := v.X.(type) {
+		case *ast.Ident:
+			return "*" + x.Name, true
+		case *ast.SelectorExpr:
+			return fmt.Sprintf("*%s.%s", x.X, x.Sel), true
+		case *ast.IndexExpr, *ast.IndexListExpr:
+			return "", true
+		}
+	}
+	return "", false
+}
+
+// statusFirst reports whether results are (int, resp) rather than
+// (resp, int).
+func statusFirst(results *types.Tuple) bool {
+	return results.Len() >= 2 &&
+		types.Identical(results.At(0).Type(), types.Typ[types.Int]) &&
+		!types.Identical(results.At(1).Type(), types.Typ[types.Int])
+}
+
+// checkResults checks that the Mode supports funcs returning results.
+func (g *Generator) checkResults(results *types.Tuple) error {
+	q := types.RelativeTo(g.pkg.Types)
+	switch g.Mode {
+	case "consumer", "job":
+		if results.Len() != 1 || !types.Identical(results.At(0).Type(), types.Universe.Lookup("error").Type()) {
+			return fmt.Errorf("returns %s, want error", types.TypeString(results, q))
+		}
+	default:
+		n, want := results.Len(), "(resp, int) or (int, resp)"
+		if g.Mode == "" {
+			want += ", optionally followed by an http.Header and a []*http.Cookie"
+			n, _, _ = extraResults(results)
+		}
+		if n != 2 || !types.Identical(results.At(0).Type(), types.Typ[types.Int]) &&
+			!types.Identical(results.At(1).Type(), types.Typ[types.Int]) {
+			return fmt.Errorf("returns %s, want %s", types.TypeString(results, q), want)
+		}
+	}
+	return nil
+}
+
+// isReader reports whether t has a Read([]byte) (int, error) method.
+func isReader(t types.Type) bool {
+	obj, _, _ := types.LookupFieldOrMethod(t, true, nil, "Read")
+	fn, ok := obj.(*types.Func)
+	if !ok {
+		return false
+	}
+	sig := fn.Type().(*types.Signature)
+	return sig.Params().Len() == 1 &&
+		types.Identical(sig.Params().At(0).Type(), types.NewSlice(types.Typ[types.Byte])) &&
+		sig.Results().Len() == 2 &&
+		types.Identical(sig.Results().At(0).Type(), types.Typ[types.Int]) &&
+		types.Identical(sig.Results().At(1).Type(), types.Universe.Lookup("error").Type())
+}
+
+// extraResults returns the number of results but for the http.Header and
+// []*http.Cookie, in that order, following the response and the status.
+func extraResults(results *types.Tuple) (n int, header, cookie bool) {
+	n = results.Len()
+	if n > 2 && isCookies(results.At(n-1).Type()) {
+		n, cookie = n-1, true
+	}
+	if n > 2 && isHeader(results.At(n-1).Type()) {
+		n, header = n-1, true
+	}
+	return n, header, cookie
+}
+
+// isHTTP reports whether t is the type of net/http named name.
+func isHTTP(t types.Type, name string) bool {
+	named, ok := t.(*types.Named)
+	return ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == "net/http" && named.Obj().Name() == name
+}
+
+// isHeader reports whether t is http.Header.
+func isHeader(t types.Type) bool {
+	return isHTTP(t, "Header")
+}
+
+// isCookies reports whether t is []*http.Cookie.
+func isCookies(t types.Type) bool {
+	s, ok := t.(*types.Slice)
+	if !ok {
+		return false
+	}
+	p, ok := s.Elem().(*types.Pointer)
+	return ok && isHTTP(p.Elem(), "Cookie")
+}
+
+// redirect returns the expression of the location a response resp of type
+// t redirects to with a 3xx status: resp itself if it is a string, or what
+// its Location() string method returns; none otherwise.
+func redirect(t types.Type) string {
+	isString := func(t types.Type) bool { return types.Identical(t, types.Typ[types.String]) }
+	switch u, _ := t.Underlying().(*types.Basic); {
+	case hasMethod(t, "Location", isString):
+		return "resp.Location()"
+	case isString(t):
+		return "resp"
+	case u != nil && u.Kind() == types.String:
+		return "string(resp)"
+	}
+	return ""
+}
+
+// hasMethod reports whether t has a method named name taking nothing and
+// returning a value of a type is.
+func hasMethod(t types.Type, name string, is func(types.Type) bool) bool {
+	obj, _, _ := types.LookupFieldOrMethod(t, true, nil, name)
+	fn, ok := obj.(*types.Func)
+	if !ok {
+		return false
+	}
+	sig := fn.Type().(*types.Signature)
+	return sig.Params().Len() == 0 && sig.Results().Len() == 1 && is(sig.Results().At(0).Type())
+}
+
+// isMeta reports whether t is a map[string]interface{}.
+func isMeta(t types.Type) bool {
+	return types.Identical(t, types.NewMap(types.Typ[types.String], types.NewInterfaceType(nil, nil)))
+}
+
+// isInt reports whether t is an int.
+func isInt(t types.Type) bool {
+	return types.Identical(t, types.Typ[types.Int])
+}
+
+// Handler is the data handler templates are executed with.
+type Handler struct {
+	Name        string   // name of the generated func
+	Func        string   // name of the func to call
+	EncodingPkg string   // name of the encoding pkg
+	T           string   // type of the parameter, qualified by its pkg name if needed
+	Imports     []string // import paths used so far
+	Chan        bool     // F returns a chan
+	Slice       bool     // F returns a slice, but a []byte
+	Pointer     bool     // F takes a *T
+	Bytes       bool     // F returns a []byte
+	StatusFirst bool     // F returns (int, resp) rather than (resp, int)
+	Header      bool     // F also returns an http.Header
+	Headers     bool     // the resp has a Headers() http.Header method
+	Cookie      bool     // F also returns a []*http.Cookie, last
+	Cookies     bool     // the resp has a Cookies() []*http.Cookie method
+	Redirect    string   // location redirected to on a 3xx status, like resp.Location()
+	Log         string   // code logging the requests, injected at the top of the handler, with the Log option
+	Metrics     string   // code measuring the requests, injected at the top of the handler, with the Metrics option
+	Trace       string   // code starting the span of the handler, injected at its top, with the Otel option
+	Limit       string   // code responding 429 Too Many Requests over the rate limit, injected at the top of the handler, with the RateLimit option
+	Session     *Session // loaded from the session cookie before the body, with the Sessions option
+	Claims      []Param  // parameters read from the bearer token before the body, with the JWT option
+	APIKey      string   // code checking the API key of the requests, injected at the top of the handler, with the APIKey option
+	Auth        string   // code authenticating the requests, injected at the top of the handler, with the Auth option
+	Requires    string   // code checking the requirements of F of the principal of the requests, injected at the top of the handler after Auth, with Requires comments
+	CSRF        string   // code checking the CSRF token of the requests, injected at the top of the handler, with the CSRF option
+	Idempotency string   // code replaying the responses of the requests repeating an Idempotency-Key, injected at the top of the handler, with the Idempotency option
+	Breaker     *Breaker // circuit breaker of the func, with the Breaker option
+	Labels      string   // pprof labels the func is called with, with the PprofLabels option, like "handler", "PutJobHandlerJSON"
+	Resp        string   // type of the resp, qualified by its pkg name if needed
+	Hook        string   // code the per-func hooks inject at the top of the handler
+	Charset     string   // code checking the charset of the request body, with the Charset option
+	Fail        bool     // errors F returns along a 4xx or 5xx status are responded by Fail, with the Problem, ErrorEncoder, Envelope or JSONAPI option
+	Envelope    string   // what the handler encodes with the Envelope, JSONAPI or HAL option, like Envelope{Data: resp}
+	Resources   string   // code building the data of the JSON:API document or the HAL resources embedded, if needed
+	MediaType   string   // Content-Type of the responses, instead of the one the encoding pkg tells, like application/vnd.api+json
+	Page        string   // parameter holding the Page F takes, with the Pagination option
+	Total       string   // total number of items of the list responded, set as X-Total-Count, with the Pagination option, like resp.Total()
+	Links       string   // func of the Link header to the other pages of the list, with the Pagination option, like PageLinks
+	Route       string   // pattern RegisterHandlers registers the handler on, if any
+	Qual        string   // qualifies the funcs of their package generating into another one, like "jober.", or their receiver, "s."
+	Receiver    string   // type of the receiver s of the generated methods, like *Server
+	Value       string   // name of the http.Handler declared with Values
+
+	ErrorStatuses []ErrorStatus // statuses responded for the errors F returns, with the ErrorStatuses option
+
+	Params []Param // parameters read from the request rather than decoded from the body
+	Cursor *Cursor // embedded in a parameter, read from the cursor query param, if any
+	Args   string  // arguments F is called with, like x, int(param1)
+
+	ContentType, ContentDisposition string // of copied responses
+}
+
+// qual returns what qualifies the funcs of the parsed package in the
+// generated code.
+func (g *Generator) qual() string {
+	if g.Receiver != "" || g.Interface != "" {
+		return "s."
+	}
+	return g.typeQual()
+}
+
+// typeQual returns what qualifies the types of the parsed package in the
+// generated code.
+func (g *Generator) typeQual() string {
+	if g.Package == "" {
+		return ""
+	}
+	if n, ok := g.names[g.pkgPath]; ok && g.pkgPath != "" {
+		return n.ident() + "."
+	}
+	return g.pkg.Name + "."
+}
+
+// checkErrorFunc checks that name, if set, is a func of the parsed package
+// the http handlers can call with (w, r, status, err), as the kind of func.
+func (g *Generator) checkErrorFunc(kind, name string) error {
+	if name == "" {
+		return nil
+	}
+	fn, ok := g.pkg.Types.Scope().Lookup(name).(*types.Func)
+	if !ok {
+		return fmt.Errorf("%s %s not found", kind, name)
+	}
+	sig := fn.Type().(*types.Signature)
+	var params []string
+	for i := 0; i < sig.Params().Len(); i++ {
+		params = append(params, types.TypeString(sig.Params().At(i).Type(), nil))
+	}
+	if strings.Join(params, ", ") != "net/http.ResponseWriter, *net/http.Request, int, error" || sig.Results().Len() > 0 {
+		return fmt.Errorf("%s %s is not a func(http.ResponseWriter, *http.Request, int, error)", kind, name)
+	}
+	return nil
+}
+
+// receiver returns the type of the receiver of the generated methods, if any.
+func (g *Generator) receiver() string {
+	if g.Interface != "" {
+		_, impl := g.interfaceNames()
+		return "*" + impl
+	}
+	if g.Receiver == "" {
+		return ""
+	}
+	return "*" + g.Receiver
+}
+
+// recvName returns the name of the type decl is a method of, if any.
+func recvName(decl *ast.FuncDecl) string {
+	if decl.Recv == nil || len(decl.Recv.List) == 0 {
+		return ""
+	}
+	t := decl.Recv.List[0].Type
+	if star, ok := t.(*ast.StarExpr); ok {
+		t = star.X
+	}
+	if ident, ok := t.(*ast.Ident); ok {
+		return ident.Name
+	}
+	return ""
+}
+
+// qualify returns the paramfullname type qualified for the generated code,
+// importing its pkg if needed.
+func (g *Generator) qualify(paramfullname string) string {
+	if g.Package == "" || types.Universe.Lookup(paramfullname) != nil {
+		return paramfullname
+	}
+	i := strings.Index(paramfullname, ".")
+	if i < 0 {
+		return g.typeQual() + paramfullname
+	}
+	for _, pkg := range g.pkg.Types.Imports() {
+		if pkg.Name() == paramfullname[:i] {
+			g.addImport(pkg.Path())
+			return g.importName(pkg.Path(), pkg.Name()) + paramfullname[i:]
+		}
+	}
+	return paramfullname
+}
+
+// handlerName returns the name of the handler of a func for an encoding, as
+// told by the Name template.
+func (g *Generator) handlerName(funcName, pkgName string) string {
+	name := g.Name
+	if name == "" {
+		name = "{{.Func}}Handler{{.Encoding}}"
+	}
+	t, err := template.New("name").Parse(name)
+	if err != nil {
+		g.errorf("invalid handler name template: %s", err)
+		return ""
+	}
+	var buf bytes.Buffer
+	err = t.Execute(&buf, struct{ Func, Encoding string }{funcName, strings.ToUpper(pkgName)})
+	if err != nil {
+		g.errorf("executing handler name template: %s", err)
+		return ""
+	}
+	if !token.IsIdentifier(buf.String()) {
+		g.errorf("handler name of %s is not an identifier: %q", funcName, buf.String())
+	}
+	return g.exported(buf.String())
+}
+
+// exported returns name with its first letter lowercased if Unexported.
+func (g *Generator) exported(name string) string {
+	if !g.Unexported || name == "" {
+		return name
+	}
+	r, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToLower(r)) + name[size:]
+}
+
+// Routes is the data the routes templates are executed with.
+type Routes struct {
+	Register   string    // name of the func registering the routes
+	Receiver   string    // type of the receiver s of the generated methods, if any
+	Routes     []Route   // of the handlers generated
+	Middleware string    // wrapping the handlers registered, like WithRequestID, if any
+	Manifest   *Manifest // declaring the RouteInfo of the routes, with the Manifest option
+	Docs       string    // func registering the OpenAPI spec handlers, with the OpenAPI option
+}
+
+// Route is a route of Routes, for each handler with a route.
+type Route struct {
+	Pattern  string // like "PUT /jobs"
+	Handler  string // name of the handler func
+	Request  string // type of the body the handler decodes, if any, like Job
+	Response string // type of its responses, like []Job
+
+	encoding string  // Import path of the encoding pkg of the handler.
+	request  request // What the Postman collection and the OpenAPI spec tell of its requests.
+	fn       string  // Name of the func of the handler, like PutJob.
+}
+
+// build generates the handler(s) of a func for an encoding, returning the
+// name of the func, with its instantiation, and the one of its http handler,
+// if any.
+func (g *Generator) build(fn Func, pkgName string, sig signature) (funcName, handler string) {
+	funcName = fn.Name + instanceName(sig.targs)
+	g.encodingPkg = pkgName
+	if err := g.checkResults(sig.results); err != nil {
+		g.errorf("%s %s", funcName, err)
+		return
+	}
+	var paramfullname string
+	if len(sig.params) == 1 {
+		paramfullname = sig.params[0].fullname
+	} else if g.Mode != "" {
+		g.errorf("%s takes %d parameters: only http handlers can call it", funcName, len(sig.params))
+		return
+	}
+	pointer := strings.HasPrefix(paramfullname, "*")
+	paramfullname = strings.TrimPrefix(paramfullname, "*")
+	result := sig.result
+	h := Handler{
+		Func:        fn.Name + typeList(sig.targs, g.qualifier),
+		EncodingPkg: pkgName,
+		T:           g.qualify(paramfullname),
+		Qual:        g.qual(),
+		Receiver:    g.receiver(),
+		Imports:     g.imports,
+		Chan:        result == chanResult,
+		Slice:       result == sliceResult,
+		Pointer:     pointer,
+		Bytes:       result == bytesResult,
+		StatusFirst: sig.statusFirst,
+		Header:      sig.header,
+		Headers:     sig.headers,
+		Cookie:      sig.cookie,
+		Cookies:     sig.cookies,
+		Redirect:    sig.redirect,
+		Route:       fn.Route,
+		Fail:        g.failing(),
+
+		ContentType:        fn.ContentType,
+		ContentDisposition: fn.ContentDisposition,
+	}
+	if h.ContentType == "" {
+		h.ContentType = g.ContentType
+	}
+	if h.ContentType == "" {
+		h.ContentType = "application/octet-stream"
+	}
+	if h.ContentDisposition == "" {
+		h.ContentDisposition = g.ContentDisposition
+	}
+	stream := fn.Stream
+	if stream == "" {
+		stream = g.Stream
+	}
+	source := fn.Source
+	if source == "" {
+		source = g.Source
+	}
+
+	switch g.Mode {
+	case "consumer":
+		h.Name = g.exported(funcName + "Consumer" + strings.ToUpper(pkgName))
+		g.buildConsumer(h)
+		return
+	case "command":
+		g.buildCommand(funcName, pkgName, paramfullname, pointer, sig.statusFirst, result == sliceResult)
+		return
+	case "job":
+		g.buildJob(funcName, pkgName, paramfullname, pointer)
+		return
+	}
+
+	h.Name = g.handlerName(funcName, pkgName)
+	g.bind(&h, fn, source, sig)
+	h.Hook = g.funcHooks(h)
+	if g.Log != "" {
+		h.Log = g.logRequests(h)
+	}
+	if g.Metrics != "" {
+		h.Metrics = g.measureRequests(h)
+	}
+	if g.PprofLabels {
+		h.Labels = fmt.Sprintf("%q, %q, %q, %q", "handler", h.Name, "encoding", pkgName)
+	}
+	if g.Otel {
+		h.Trace = g.traceRequests(h)
+	}
+	h.Limit = g.limitRequests(h, fn, funcName)
+	h.Breaker = g.breaker(fn, funcName)
+	if g.Auth && !fn.Public {
+		h.Auth = g.authenticate()
+	}
+	h.Requires = g.authorize(fn, funcName, h.Auth != "")
+	h.APIKey = g.checkAPIKey(h, fn, funcName)
+	if g.CSRF || fn.CSRF {
+		h.CSRF = g.checkCSRF()
+	}
+	if g.transcodes(pkgName) && h.T != "" {
+		g.addImport("io")
+		g.addImport("golang.org/x/text/encoding/htmlindex")
+	} else if g.Charset != "" && g.Mode == "" && h.T != "" {
+		g.addImport("mime")
+		g.addImport("strings")
+		if g.Charset == "transcode" {
+			g.addImport("io")
+			g.addImport("golang.org/x/text/encoding/htmlindex")
+		}
+		h.Charset = g.code("charset", struct{ Charset string }{g.Charset})
+	}
+	t := sig.results.At(0).Type() // Of the resp.
+	if sig.statusFirst {
+		t = sig.results.At(1).Type()
+	}
+	h.Resp = types.TypeString(t, g.qualifier)
+	encoded := t // By the adapter.
+	switch {
+	case result == readerResult || result == bytesResult:
+		encoded = nil // Copied as is.
+	case result == chanResult:
+		encoded = t.Underlying().(*types.Chan).Elem()
+	case result == sliceResult && stream == "ndjson":
+		encoded = t.Underlying().(*types.Slice).Elem()
+	}
+	if err := g.checkCodec(pkgName, g.request.body, encoded); err != nil {
+		g.errorf("%s %s", funcName, err)
+		return
+	}
+	g.request.t, g.request.resp, g.request.result = h.T, h.Resp, t
+	if h.Route != "" {
+		handler := h.Name
+		if g.receiver() != "" {
+			handler = "s." + handler
+		}
+		g.routes = append(g.routes, Route{
+			Pattern:  h.Route,
+			Handler:  handler,
+			Request:  h.T,
+			Response: h.Resp,
+			request:  g.request,
+			fn:       funcName,
+		})
+	}
+
+	name := "handler"
+	switch {
+	case result == readerResult || result == bytesResult:
+		g.addImport("io")
+		if h.Bytes {
+			g.addImport("bytes")
+		}
+		name = "reader"
+	case stream == "ndjson" && result != plainResult:
+		g.addImport("bytes")
+		name = "ndjson"
+	case result == chanResult:
+		g.addImport("bytes")
+		g.addImport("fmt")
+		if h.T != "" {
+			g.addImport("io")
+		}
+		name = "events"
+	}
+	if g.Interface != "" && h.Name == funcName {
+		g.errorf("handler name of %s is the one of the method it calls", funcName)
+	}
+	if name == "handler" {
+		h.ErrorStatuses = g.errorStatuses()
+	}
+	if g.JSONAPI && g.Mode == "" {
+		g.enveloped = true
+		if name == "handler" {
+			h.Resources, h.Envelope = g.jsonapiDocument(funcName, t, h.Slice)
+			if h.Envelope != "" {
+				h.MediaType = jsonapiMediaType
+			}
+		}
+	}
+	if g.HAL && g.Mode == "" && name == "handler" {
+		g.enveloped = true
+		h.Resources, h.Envelope = g.halDocument(t, h.Slice)
+		h.MediaType = halMediaType
+	}
+	if g.Envelope && g.Mode == "" {
+		g.enveloped = true
+		if name == "handler" {
+			h.Envelope = g.exported("Envelope") + "{Data: resp}"
+			if sig.meta {
+				h.Envelope = g.exported("Envelope") + "{Data: resp, Meta: resp.Meta()}"
+			}
+		}
+	}
+	if name == "handler" && (g.Idempotency || fn.Idempotency) {
+		h.Idempotency = g.replayRequests(h, fn)
+	}
+	if g.Pagination && name == "handler" && sig.total {
+		h.Total = "resp.Total()"
+		g.addImport("strconv")
+		if h.Page != "" {
+			h.Links = g.pagination().Links
+		}
+	}
+	g.execute(name, h)
+	if g.Interface != "" {
+		g.handlers = append(g.handlers, h.Name)
+	}
+	handler = h.Name
+
+	if g.Values {
+		h.Value = g.exported(funcName + strings.ToUpper(pkgName))
+		if h.Value == h.Name {
+			g.errorf("handler name of %s is the one of its http.Handler: %s", funcName, h.Name)
+		}
+		g.execute("value", h)
+	}
+
+	if name == "handler" && (g.WebSocket || fn.WebSocket) {
+		if err := checkWebSocket(h); err != nil {
+			g.errorf("%s %s", funcName, err)
+			return
+		}
+		g.addImport("bytes")
+		g.addImport("github.com/gorilla/websocket")
+		h.Name = g.exported(funcName + "WebSocket" + strings.ToUpper(pkgName))
+		g.execute("websocket", h)
+		if g.Interface != "" {
+			g.handlers = append(g.handlers, h.Name)
+		}
+	}
+	return funcName, handler
+}
@@ -0,0 +1,111 @@
+package handlergen
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"strings"
+	"unicode"
+
+	"github.com/azr/generators/loader"
+)
+
+// ParseInstances parses a comma-separated list of instantiations of generic
+// funcs, like Put[Job],Map[string,Job], into Funcs.
+func ParseInstances(list string) ([]Func, error) {
+	var funcs []Func
+	for _, s := range splitTypeList(list) {
+		i := strings.Index(s, "[")
+		if i <= 0 || !strings.HasSuffix(s, "]") {
+			return nil, fmt.Errorf("invalid instantiation %s: want F[T]", s)
+		}
+		funcs = append(funcs, Func{
+			Name:     s[:i],
+			TypeArgs: splitTypeList(s[i+1 : len(s)-1]),
+		})
+	}
+	return funcs, nil
+}
+
+// splitTypeList splits list around the commas that are not between
+// brackets or parentheses, like the ones of map[string]Pair[int,Job].
+func splitTypeList(list string) []string {
+	var fields []string
+	depth, start := 0, 0
+	for i, c := range list {
+		switch c {
+		case '[', '(':
+			depth++
+		case ']', ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				fields = append(fields, strings.TrimSpace(list[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	if last := strings.TrimSpace(list[start:]); last != "" || len(fields) > 0 {
+		fields = append(fields, last)
+	}
+	return fields
+}
+
+// instantiate returns the signature of fn instantiated with the typeArgs,
+// written as in the parsed package.
+func instantiate(pkg *loader.Package, fn *types.Func, typeArgs []string) (*types.Signature, []types.Type, error) {
+	var targs []types.Type
+	for _, arg := range typeArgs {
+		tv, err := types.Eval(pkg.Fset, pkg.Types, token.NoPos, arg)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid type argument %s: %s", arg, err)
+		}
+		if !tv.IsType() {
+			return nil, nil, fmt.Errorf("type argument %s is not a type", arg)
+		}
+		targs = append(targs, tv.Type)
+	}
+	sig, err := types.Instantiate(nil, fn.Type(), targs, true)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot instantiate %s%s: %s", fn.Name(), typeList(targs, types.RelativeTo(pkg.Types)), err)
+	}
+	return sig.(*types.Signature), targs, nil
+}
+
+// qualifier qualifies types as written in the generated code, importing the
+// pkgs they refer to.
+func (g *Generator) qualifier(pkg *types.Package) string {
+	if pkg == g.pkg.Types {
+		return strings.TrimSuffix(g.typeQual(), ".")
+	}
+	g.addImport(pkg.Path())
+	return g.importName(pkg.Path(), pkg.Name())
+}
+
+// typeList returns the targs type list, like [string, Job], qualified by q;
+// it is empty without targs.
+func typeList(targs []types.Type, q types.Qualifier) string {
+	if len(targs) == 0 {
+		return ""
+	}
+	var args []string
+	for _, t := range targs {
+		args = append(args, types.TypeString(t, q))
+	}
+	return "[" + strings.Join(args, ", ") + "]"
+}
+
+// instanceName returns what names the instantiation of a func with targs,
+// like StringJob for [string, Job].
+func instanceName(targs []types.Type) string {
+	var name string
+	for _, t := range targs {
+		s := types.TypeString(t, func(*types.Package) string { return "" })
+		for _, word := range strings.FieldsFunc(s, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+		}) {
+			name += strings.ToUpper(word[:1]) + word[1:]
+		}
+	}
+	return name
+}
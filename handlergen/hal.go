@@ -0,0 +1,218 @@
+package handlergen
+
+import (
+	"go/types"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// halMediaType is the Content-Type of the HAL resources.
+const halMediaType = "application/hal+json"
+
+// HAL is the data the hal template is executed with: the names of the types
+// of the HAL resources it declares, and of encoding/json.
+type HAL struct {
+	Resource, Link, JSON string
+}
+
+// halNames returns the names of the types of the HAL resources.
+func (g *Generator) halNames() HAL {
+	return HAL{
+		Resource: g.exported("HALResource"),
+		Link:     g.exported("HALLink"),
+		JSON:     g.importName("encoding/json", "json"),
+	}
+}
+
+// halRoute is a GET route of a func, linked to from the HAL resources.
+type halRoute struct {
+	path     string       // like /jobs/{id}
+	resource *types.Named // type of the resources the func responds, if named
+}
+
+// halRoutes returns the GET routes of funcs.
+func (g *Generator) halRoutes(funcs []Func) []halRoute {
+	var routes []halRoute
+	for _, fn := range funcs {
+		method, path := "GET", fn.Route
+		if i := strings.Index(fn.Route, " "); i >= 0 {
+			method, path = fn.Route[:i], strings.TrimSpace(fn.Route[i+1:])
+		}
+		if method != "GET" || !strings.HasPrefix(path, "/") {
+			continue
+		}
+		routes = append(routes, halRoute{path: path, resource: g.funcResource(fn.Name)})
+	}
+	return routes
+}
+
+// funcResource returns the type of the responses of the func or method
+// named name, if named.
+func (g *Generator) funcResource(name string) *types.Named {
+	var obj types.Object
+	if recv := g.Receiver + g.Interface; recv != "" {
+		if t, ok := g.pkg.Types.Scope().Lookup(recv).(*types.TypeName); ok {
+			recvType := t.Type()
+			if !types.IsInterface(recvType) {
+				recvType = types.NewPointer(recvType)
+			}
+			obj, _, _ = types.LookupFieldOrMethod(recvType, true, g.pkg.Types, name)
+		}
+	} else {
+		obj = g.pkg.Types.Scope().Lookup(name)
+	}
+	fn, ok := obj.(*types.Func)
+	if !ok {
+		return nil
+	}
+	results := fn.Type().(*types.Signature).Results()
+	if results.Len() < 2 {
+		return nil
+	}
+	t := results.At(0).Type()
+	if types.Identical(t, types.Typ[types.Int]) {
+		t = results.At(1).Type()
+	}
+	return namedType(t)
+}
+
+// namedType returns t, or the type t points to, if named.
+func namedType(t types.Type) *types.Named {
+	if p, ok := t.(*types.Pointer); ok {
+		t = p.Elem()
+	}
+	named, _ := t.(*types.Named)
+	return named
+}
+
+// halDocument returns the HAL resource the http handler of a func encodes
+// for its resp of type t, a slice or not, along with the code building the
+// resources it embeds before, if any.
+func (g *Generator) halDocument(t types.Type, slice bool) (code, document string) {
+	n := g.halNames()
+	if !slice {
+		return "", n.Resource + "{Links: " + g.halLinks(t, "resp", true) + ", Resource: resp}"
+	}
+	elem := t.Underlying().(*types.Slice).Elem()
+	rel := "items"
+	if named := namedType(elem); named != nil {
+		rel = resourceType(named.Obj().Name())
+	}
+	code = "embedded := make([]" + n.Resource + ", len(resp))\n" +
+		"for i, v := range resp {\n" +
+		"embedded[i] = " + n.Resource + "{Links: " + g.halLinks(elem, "v", false) + ", Resource: v}\n" +
+		"}"
+	return code, n.Resource + "{Links: map[string]" + n.Link + "{\"self\": {Href: r.URL.RequestURI()}}, " +
+		"Embedded: map[string]interface{}{" + strconv.Quote(rel) + ": embedded}}"
+}
+
+// halLinks returns the links of the v resource of type t: to itself, by the
+// GET route of its type, and to the GET routes under the one of its type,
+// named after their last segment, like runs for /jobs/{id}/runs. The link
+// to itself is the one requested if its type has no route and requested is
+// set.
+func (g *Generator) halLinks(t types.Type, v string, requested bool) string {
+	links := make(map[string]string) // Hrefs, by relation.
+	named := namedType(t)
+	for _, self := range g.halIndex {
+		if named == nil || self.resource != named {
+			continue
+		}
+		href, ok := g.href(t, v, self.path)
+		if !ok {
+			continue
+		}
+		links["self"] = href
+		prefix := strings.TrimSuffix(self.path, "/") + "/"
+		for _, route := range g.halIndex {
+			rest := strings.TrimPrefix(route.path, prefix)
+			if rest == route.path || rest == "" {
+				continue
+			}
+			rel := ""
+			for _, seg := range strings.Split(rest, "/") {
+				if seg != "" && !strings.HasPrefix(seg, "{") {
+					rel = seg
+				}
+			}
+			if rel == "" || links[rel] != "" {
+				continue
+			}
+			if href, ok := g.href(t, v, route.path); ok {
+				links[rel] = href
+			}
+		}
+		break
+	}
+	if links["self"] == "" && requested {
+		links["self"] = "r.URL.RequestURI()"
+	}
+	if len(links) == 0 {
+		return "nil"
+	}
+	var rels []string
+	for rel := range links {
+		rels = append(rels, rel)
+	}
+	sort.Strings(rels)
+	var fields []string
+	for _, rel := range rels {
+		fields = append(fields, strconv.Quote(rel)+": {Href: "+links[rel]+"}")
+	}
+	return "map[string]" + g.halNames().Link + "{" + strings.Join(fields, ", ") + "}"
+}
+
+// href returns the expression of the path of the v resource of type t at
+// the route path, its wildcards filled with the fields of v named alike, like
+// "/jobs/" + url.PathEscape(fmt.Sprint(v.ID)) for /jobs/{id}. It fails if v
+// has no field for a wildcard.
+func (g *Generator) href(t types.Type, v, path string) (string, bool) {
+	var parts []string
+	lit := ""
+	for _, seg := range strings.Split(path, "/")[1:] {
+		lit += "/"
+		if seg == "{$}" {
+			continue
+		}
+		if !strings.HasPrefix(seg, "{") || !strings.HasSuffix(seg, "}") {
+			lit += seg
+			continue
+		}
+		name := strings.Trim(seg, "{}")
+		field := fieldNamed(t, strings.TrimSuffix(name, "..."))
+		if field == "" {
+			return "", false
+		}
+		g.addImport("fmt")
+		value := "fmt.Sprint(" + v + "." + field + ")"
+		if !strings.HasSuffix(name, "...") {
+			g.addImport("net/url")
+			value = "url.PathEscape(" + value + ")"
+		}
+		parts = append(parts, strconv.Quote(lit), value)
+		lit = ""
+	}
+	if lit != "" {
+		parts = append(parts, strconv.Quote(lit))
+	}
+	return strings.Join(parts, " + "), true
+}
+
+// fieldNamed returns the name of the exported field of the t struct, or of
+// the struct t points to, named like name but for the case, if any.
+func fieldNamed(t types.Type, name string) string {
+	if p, ok := t.(*types.Pointer); ok {
+		t = p.Elem()
+	}
+	s, ok := t.Underlying().(*types.Struct)
+	if !ok {
+		return ""
+	}
+	for i := 0; i < s.NumFields(); i++ {
+		if f := s.Field(i); f.Exported() && strings.EqualFold(f.Name(), name) {
+			return f.Name()
+		}
+	}
+	return ""
+}
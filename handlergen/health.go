@@ -0,0 +1,79 @@
+package handlergen
+
+import (
+	"errors"
+	"strings"
+)
+
+// Health is the data the health_checks template is executed with, in
+// health mode.
+type Health struct {
+	Checker  string          // type of the checks, funcs of a context returning an error
+	Checkers string          // package var of the checks /healthz and /readyz run, by name
+	Ready    string          // package var of the checks /readyz only runs, by name
+	Status   string          // type of the responses of the handlers
+	Check    string          // type of the result of a check in a Status
+	Register string          // func registering the handlers of the first encoding
+	Handlers []HealthHandler // of each encoding
+}
+
+// HealthHandler is the data the health template is executed with, for each
+// encoding.
+type HealthHandler struct {
+	Healthz     string // name of the handler running the Checkers
+	Readyz      string // name of the handler running the Checkers and the Ready ones
+	EncodingPkg string // name of the encoding pkg
+}
+
+// healthNames returns the Health the declarations are executed with.
+func (g *Generator) healthNames() Health {
+	return Health{
+		Checker:  g.exported("Checker"),
+		Checkers: g.exported("HealthCheckers"),
+		Ready:    g.exported("ReadyCheckers"),
+		Status:   g.exported("HealthStatus"),
+		Check:    g.exported("HealthCheck"),
+		Register: g.exported("RegisterHealthHandlers"),
+	}
+}
+
+// buildHealth generates the /healthz and /readyz handlers of each encoding,
+// then the checks they run and the func registering them.
+func (g *Generator) buildHealth() error {
+	if len(g.funcs) > 0 {
+		return errors.New("cannot generate health checks for funcs")
+	}
+	if len(g.encodings) == 0 {
+		return errors.New("no encoding to generate health checks for")
+	}
+	n := g.healthNames()
+	for _, encoding := range g.encodings {
+		g.addImport(encoding.path)
+		pkgName := g.importName(encoding.path, encoding.name)
+		h := HealthHandler{
+			Healthz:     g.exported("HealthzHandler" + strings.ToUpper(pkgName)),
+			Readyz:      g.exported("ReadyzHandler" + strings.ToUpper(pkgName)),
+			EncodingPkg: pkgName,
+		}
+		g.execute("health", struct {
+			HealthHandler
+			Health
+		}{h, n})
+		n.Handlers = append(n.Handlers, h)
+	}
+	g.addImport("context")
+	g.addImport("sort")
+	g.execute("health_checks", n)
+	return nil
+}
+
+// healthRegister returns the func registering the handlers of a previous
+// health run, if the parsed package has one.
+func (g *Generator) healthRegister() string {
+	name := g.healthNames().Register
+	obj := g.pkg.Types.Scope().Lookup(name)
+	if obj == nil || !g.generated(obj.Pos()) {
+		return ""
+	}
+	return name
+}
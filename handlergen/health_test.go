@@ -0,0 +1,76 @@
+package handlergen
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// healthTest is the test of the handlers of a health run and of the http
+// handlers generated afterwards, registered on one mux.
+const healthTest = `package jobs
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHealth(t *testing.T) {
+	mux := http.NewServeMux()
+	RegisterHandlers(mux)
+	RegisterHealthHandlers(mux)
+	for _, test := range []struct {
+		method, target string
+	}{
+		{"GET", "/healthz"},
+		{"GET", "/readyz"},
+		{"PUT", "/jobs"},
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(test.method, test.target, strings.NewReader("{\"ID\": \"1\"}")))
+		if w.Code != 200 {
+			t.Errorf("%s %s: responded %d, want 200", test.method, test.target, w.Code)
+		}
+	}
+}
+`
+
+func TestHealthRegisteredOnce(t *testing.T) {
+	dir := testModule(t, map[string]string{"jobs.go": `package jobs
+
+type Job struct{ ID string }
+
+func PutJob(j Job) (Job, int) {
+	return j, 200
+}
+`})
+	g := &Generator{Mode: "health"}
+	if err := g.AddEncoding("encoding/json"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Parse(dir); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := g.Render(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "generated_health.go"), buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	g = &Generator{}
+	if err := g.Add(Func{Name: "PutJob", Route: "PUT /jobs"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := generate(dir, g, nil, "encoding/json"); err != nil {
+		t.Fatal(err)
+	}
+	// Written once generated, not to be type-checked along the package.
+	if err := ioutil.WriteFile(filepath.Join(dir, "health_test.go"), []byte(healthTest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	goTest(t, dir, g)
+}
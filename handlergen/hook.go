@@ -0,0 +1,118 @@
+package handlergen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// HookRequest is sent to the stdin of hook executables.
+type HookRequest struct {
+	Stage string `json:"stage"` // pre-parse, per-func or post-generate
+
+	// pre-parse
+	Files     []string `json:"files,omitempty"`
+	Funcs     []string `json:"funcs,omitempty"`
+	Encodings []string `json:"encodings,omitempty"`
+
+	// per-func
+	Func     string `json:"func,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+	Type     string `json:"type,omitempty"`
+
+	// post-generate
+	Output string `json:"output,omitempty"`
+	Source string `json:"source,omitempty"`
+}
+
+// HookResponse is read from the stdout of hook executables, if any.
+type HookResponse struct {
+	Code    string   `json:"code,omitempty"`    // per-func: injected into the handler
+	Imports []string `json:"imports,omitempty"` // per-func: needed by Code
+	Source  string   `json:"source,omitempty"`  // post-generate: replaces the output
+}
+
+// runHooks runs every hook with req and returns their responses.
+func (g *Generator) runHooks(req HookRequest) ([]HookResponse, error) {
+	var resps []HookResponse
+	for _, hook := range g.Hooks {
+		g.logf("running hook %s %s", hook, req.Stage)
+		resp, ok, err := runHook(hook, req)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			resps = append(resps, resp)
+		}
+	}
+	return resps, nil
+}
+
+// runHook runs hook with req; ok is false when it replied nothing.
+func runHook(hook string, req HookRequest) (resp HookResponse, ok bool, err error) {
+	in, err := json.Marshal(req)
+	if err != nil {
+		return resp, false, err
+	}
+	var out bytes.Buffer
+	cmd := exec.Command(hook, req.Stage)
+	cmd.Stdin = bytes.NewReader(in)
+	cmd.Stdout = &out
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return resp, false, fmt.Errorf("hook %s %s: %s", hook, req.Stage, err)
+	}
+	if len(bytes.TrimSpace(out.Bytes())) == 0 {
+		return resp, false, nil
+	}
+	if err := json.Unmarshal(out.Bytes(), &resp); err != nil {
+		return resp, false, fmt.Errorf("hook %s %s: invalid response: %s", hook, req.Stage, err)
+	}
+	return resp, true, nil
+}
+
+// funcHooks returns the code the per-func hooks inject into the handler of h.
+func (g *Generator) funcHooks(h Handler) string {
+	var code []string
+	resps, err := g.runHooks(HookRequest{
+		Stage:    "per-func",
+		Func:     h.Func,
+		Encoding: h.EncodingPkg,
+		Type:     h.T,
+	})
+	if err != nil {
+		g.errorf("%s", err)
+	}
+	for _, resp := range resps {
+		for _, path := range resp.Imports {
+			g.addImport(path)
+		}
+		if resp.Code != "" {
+			code = append(code, resp.Code)
+		}
+	}
+	return strings.Join(code, "\n")
+}
+
+// postGenerate lets the post-generate hooks replace src, each hook getting
+// the source the previous one replied.
+func (g *Generator) postGenerate(output string, src []byte) ([]byte, error) {
+	for _, hook := range g.Hooks {
+		g.logf("running hook %s post-generate", hook)
+		resp, ok, err := runHook(hook, HookRequest{
+			Stage:  "post-generate",
+			Output: output,
+			Source: string(src),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if ok && resp.Source != "" {
+			src = []byte(resp.Source)
+		}
+	}
+	return src, nil
+}
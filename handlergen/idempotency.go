@@ -0,0 +1,58 @@
+package handlergen
+
+import "strings"
+
+// Idempotency is the data the idempotency templates are executed with, with
+// the Idempotency option.
+type Idempotency struct {
+	Store    string // interface of the stores of the responses, like IdempotencyStore
+	Response string // type of the responses stored, like IdempotentResponse
+	InFlight string // error of Begin while a request holding the key is in progress
+	New      string // func returning the store keeping them in memory
+	Var      string // package var of the store the http handlers use
+	Handler  string // name of the handler replaying its responses, if any
+	Scope    string // expression of who the keys of its requests are scoped to, like fmt.Sprint(principal), if any
+}
+
+// idempotencyNames returns the Idempotency the declarations are executed
+// with.
+func (g *Generator) idempotencyNames() Idempotency {
+	return Idempotency{
+		Store:    g.exported("IdempotencyStore"),
+		Response: g.exported("IdempotentResponse"),
+		InFlight: g.exported("ErrIdempotencyInFlight"),
+		New:      g.exported("NewIdempotencyStore"),
+		Var:      g.exported("HandlerIdempotency"),
+	}
+}
+
+// replayRequests returns the code of the h http handler replaying the
+// response stored for the Idempotency-Key of the request, if any, injected
+// at its top, or else storing the one it responds. The keys are scoped to
+// the principal of the request, with Auth, and to its API key, with APIKey,
+// so that a client cannot replay the responses of another.
+func (g *Generator) replayRequests(h Handler, fn Func) string {
+	g.replaying = true
+	g.addImport("bytes")
+	g.addImport("crypto/sha256")
+	g.addImport("encoding/hex")
+	g.addImport("errors")
+	g.addImport("io")
+	n := g.idempotencyNames()
+	n.Handler = h.Name
+	var scopes []string
+	if h.Auth != "" {
+		g.addImport("fmt")
+		scopes = append(scopes, "fmt.Sprint(principal)")
+	}
+	if h.APIKey != "" {
+		spec := fn.APIKey
+		if spec == "" {
+			spec = g.APIKey
+		}
+		key, _ := apiKeySource(spec) // Checked along h.APIKey.
+		scopes = append(scopes, key)
+	}
+	n.Scope = strings.Join(scopes, ` + " " + `)
+	return g.code("idempotency", n)
+}
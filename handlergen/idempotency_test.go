@@ -0,0 +1,85 @@
+package handlergen
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// idempotencyTest is the test of the http handler of PutJob replaying its
+// responses: to the requests of the same API key repeating the body, without
+// the cookies of the first one, and 422 to the ones changing it.
+const idempotencyTest = `package jobs
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type keys struct{}
+
+func (keys) ValidateAPIKey(ctx context.Context, key, handler string) (bool, bool, error) {
+	return true, true, nil
+}
+
+func TestIdempotency(t *testing.T) {
+	HandlerAPIKeys = keys{}
+	for i, test := range []struct {
+		apiKey, body string
+		status, calls int
+		cookie        bool
+	}{
+		{"a", "{\"ID\": \"1\"}", 200, 1, true},
+		{"a", "{\"ID\": \"1\"}", 200, 1, false}, // replayed
+		{"a", "{\"ID\": \"2\"}", 422, 1, false},
+		{"b", "{\"ID\": \"1\"}", 200, 2, true}, // of another client
+	} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/jobs", strings.NewReader(test.body))
+		r.Header.Set("Idempotency-Key", "k")
+		r.Header.Set("X-API-Key", test.apiKey)
+		PutJobHandlerJSON(w, r)
+		if w.Code != test.status || calls != test.calls {
+			t.Errorf("request %d: responded %d, after %d calls, want %d, after %d", i, w.Code, calls, test.status, test.calls)
+		}
+		if cookie := w.Header().Get("Set-Cookie") != ""; cookie != test.cookie {
+			t.Errorf("request %d: Set-Cookie %v, want %v", i, cookie, test.cookie)
+		}
+		if test.status == 200 && !strings.Contains(w.Body.String(), fmt.Sprintf("\"Calls\":%d", test.calls)) {
+			t.Errorf("request %d: responded %s, of the call %d", i, w.Body, test.calls)
+		}
+	}
+}
+`
+
+func TestIdempotencyScope(t *testing.T) {
+	dir := testModule(t, map[string]string{"jobs.go": `package jobs
+
+import "net/http"
+
+type Job struct {
+	ID    string
+	Calls int
+}
+
+var calls int
+
+func PutJob(j Job) (Job, int, []*http.Cookie) {
+	calls++
+	j.Calls = calls
+	return j, 200, []*http.Cookie{{Name: "session", Value: "s"}}
+}
+`})
+	g := &Generator{Idempotency: true, APIKey: "header"}
+	if err := generate(dir, g, []string{"PutJob"}, "encoding/json"); err != nil {
+		t.Fatal(err)
+	}
+	// Written once generated, not to be type-checked along the package.
+	if err := ioutil.WriteFile(filepath.Join(dir, "idempotency_test.go"), []byte(idempotencyTest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	goTest(t, dir, g)
+}
@@ -0,0 +1,141 @@
+package handlergen
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// pkgName is how the generated code refers to an imported pkg.
+type pkgName struct {
+	name  string // Declared by the pkg, like json.
+	alias string // Used instead of name, like goccyjson, if another pkg is named name.
+}
+
+// ident returns the identifier the generated code refers to the pkg by.
+func (n pkgName) ident() string {
+	if n.alias != "" {
+		return n.alias
+	}
+	return n.name
+}
+
+// importName returns the identifier the generated code refers to the pkg at
+// path, named name, by: name, or, if another pkg it refers to is named so,
+// an alias made of the element of path before the name, like goccyjson for
+// github.com/goccy/go-json along encoding/json, or numbered, like json2.
+func (g *Generator) importName(path, name string) string {
+	if n, ok := g.names[path]; ok {
+		return n.ident()
+	}
+	if g.names == nil {
+		g.names = make(map[string]pkgName)
+	}
+	n := pkgName{name: name}
+	taken := func(ident string) bool { return g.taken(ident, path) }
+	if taken(name) {
+		n.alias = aliasOf(path, name, taken)
+	}
+	g.names[path] = n
+	return n.ident()
+}
+
+// taken reports whether the generated code refers to an imported pkg other
+// than the one at except by ident, counting the pkgs imported by path only
+// with their last element.
+func (g *Generator) taken(ident, except string) bool {
+	for imported, n := range g.names {
+		if imported != except && n.ident() == ident {
+			return true
+		}
+	}
+	for _, imported := range g.imports {
+		if _, ok := g.names[imported]; !ok && imported != except && path.Base(imported) == ident {
+			return true
+		}
+	}
+	return false
+}
+
+// aliasOf returns an alias of the pkg at pkgPath, named name, that is not
+// taken.
+func aliasOf(pkgPath, name string, taken func(string) bool) string {
+	elems := strings.Split(pkgPath, "/")
+	if len(elems) > 1 {
+		prefix := strings.Map(func(r rune) rune {
+			if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+				return unicode.ToLower(r)
+			}
+			return -1
+		}, elems[len(elems)-2])
+		if alias := prefix + name; prefix != "" && !unicode.IsDigit(rune(prefix[0])) && !taken(alias) {
+			return alias
+		}
+	}
+	for i := 2; ; i++ {
+		if alias := fmt.Sprintf("%s%d", name, i); !taken(alias) {
+			return alias
+		}
+	}
+}
+
+// nameEncodings resets how the generated code refers to the imported pkgs,
+// naming the encoding pkgs first, the standard ones then the others by path,
+// so that they keep the same names, and the handlers named after them too,
+// whatever the order they are added in and the file they are generated to.
+func (g *Generator) nameEncodings() {
+	g.names = nil
+	// The pkgs a previous render imported do not count.
+	imports := g.imports
+	g.imports = nil
+	defer func() { g.imports = imports }()
+	seen := make(map[string]bool)
+	var encodings []encodingPkg
+	add := func(encoding encodingPkg) {
+		if !seen[encoding.path] {
+			seen[encoding.path] = true
+			encodings = append(encodings, encoding)
+		}
+	}
+	for _, encoding := range g.encodings {
+		add(encoding)
+	}
+	for _, fn := range g.funcs {
+		for _, path := range fn.Encodings {
+			if encoding, err := importEncoding(path); err == nil {
+				add(encoding)
+			}
+		}
+	}
+	sort.Slice(encodings, func(i, j int) bool {
+		a, b := encodings[i].path, encodings[j].path
+		if standard(a) != standard(b) {
+			return standard(a)
+		}
+		return a < b
+	})
+	for _, encoding := range encodings {
+		g.importName(encoding.path, encoding.name)
+	}
+}
+
+// EncodingAlias returns the identifier the generated code refers to the
+// encoding pkg at path by: its name, like json for encoding/json, or an
+// alias if another encoding pkg generated for is named so, like goccyjson
+// for github.com/goccy/go-json.
+func (g *Generator) EncodingAlias(path string) (string, error) {
+	encoding, err := importEncoding(path)
+	if err != nil {
+		return "", err
+	}
+	g.nameEncodings()
+	return g.importName(path, encoding.name), nil
+}
+
+// standard reports whether the pkg at path is of the standard library, its
+// first element holding no dot.
+func standard(path string) bool {
+	return !strings.Contains(strings.SplitN(path, "/", 2)[0], ".")
+}
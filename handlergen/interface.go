@@ -0,0 +1,77 @@
+package handlergen
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Interface is the data the interface template is executed with.
+type Interface struct {
+	Name      string   // of the struct of handlers, like JobAPIHandlers
+	New       string   // name of the constructor, like NewJobAPIHandlers
+	Impl      string   // name of the type the handlers are methods of, embedding the interface
+	Interface string   // type of the interface, qualified by its pkg name if needed
+	Handlers  []string // names of the handlers, fields of the struct
+}
+
+// interfaceNames returns the names of the struct of handlers and of the
+// type the handlers are methods of, for the Interface.
+func (g *Generator) interfaceNames() (handlers, impl string) {
+	r, size := utf8.DecodeRuneInString(g.Interface)
+	return g.exported(g.Interface + "Handlers"), string(unicode.ToLower(r)) + g.Interface[size:] + "Impl"
+}
+
+// checkInterface checks that the Interface can be generated for.
+func (g *Generator) checkInterface() error {
+	if g.Mode != "" {
+		return fmt.Errorf("cannot generate %ss for interface %s", g.Mode, g.Interface)
+	}
+	if g.Receiver != "" {
+		return fmt.Errorf("cannot generate for methods of %s and of interface %s", g.Receiver, g.Interface)
+	}
+	obj, ok := g.pkg.Types.Scope().Lookup(g.Interface).(*types.TypeName)
+	if !ok || !types.IsInterface(obj.Type()) {
+		return fmt.Errorf("interface %s not found", g.Interface)
+	}
+	return nil
+}
+
+// interfaceType returns the declaration of the Interface, if any.
+func (g *Generator) interfaceType() *ast.InterfaceType {
+	for _, file := range g.pkg.Files {
+		for _, decl := range file.Decls {
+			decl, ok := decl.(*ast.GenDecl)
+			if !ok {
+				continue
+			}
+			for _, spec := range decl.Specs {
+				spec, ok := spec.(*ast.TypeSpec)
+				if !ok || spec.Name.Name != g.Interface {
+					continue
+				}
+				if t, ok := spec.Type.(*ast.InterfaceType); ok {
+					return t
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// interfaceMethods returns the methods declared by t, by name, along with
+// their doc.
+func interfaceMethods(t *ast.InterfaceType) (names []*ast.Ident, funcs []*ast.FuncType, docs []*ast.CommentGroup) {
+	for _, field := range t.Methods.List {
+		ft, ok := field.Type.(*ast.FuncType)
+		if !ok || len(field.Names) != 1 {
+			continue // Embedded interface.
+		}
+		names = append(names, field.Names[0])
+		funcs = append(funcs, ft)
+		docs = append(docs, field.Doc)
+	}
+	return names, funcs, docs
+}
@@ -0,0 +1,123 @@
+package handlergen
+
+import (
+	"fmt"
+	"strings"
+
+	"go/types"
+)
+
+// EnvField is a field of the parameter loaded from the environment.
+type EnvField struct {
+	Env   string // name of the variable
+	Field string // Go name of the field
+	Parse string // call parsing v into (parsed, err); none for strings
+	Type  string // the field is converted to, if needed
+	Split bool   // comma-separated []string
+}
+
+// buildJob generates a job wrapper for a single func and encoding.
+func (g *Generator) buildJob(funcName, pkgName, paramfullname string, pointer bool) {
+	g.addImport("fmt")
+	g.addImport("os")
+
+	var fields []EnvField
+	if st, ok := g.lookupType(paramfullname).Underlying().(*types.Struct); ok {
+		for i := 0; i < st.NumFields(); i++ {
+			field := st.Field(i)
+			if !field.Exported() || field.Anonymous() {
+				continue
+			}
+			f, ok := g.envField(field.Type(), "v")
+			if !ok {
+				g.warnf("%s.%s: cannot read type %s from the environment, skipped", paramfullname, field.Name(), field.Type())
+				continue
+			}
+			f.Field = field.Name()
+			f.Env = g.EnvPrefix + strings.ToUpper(strings.Replace(kebabCase(field.Name()), "-", "_", -1))
+			fields = append(fields, f)
+		}
+	} else {
+		g.warnf("%s is not a struct, job %s will only be loaded from a file", paramfullname, funcName)
+	}
+
+	g.execute("job", struct {
+		Name        string
+		Func        string
+		EncodingPkg string
+		T           string
+		Pointer     bool
+		Qual        string
+		Receiver    string
+		Fields      []EnvField
+	}{
+		Name:        g.exported(funcName + "Job" + strings.ToUpper(pkgName)),
+		Func:        funcName,
+		EncodingPkg: pkgName,
+		T:           g.qualify(paramfullname),
+		Pointer:     pointer,
+		Qual:        g.qual(),
+		Receiver:    g.receiver(),
+		Fields:      fields,
+	})
+}
+
+// envField tells how a t is read from an environment variable, or another
+// string v.
+func (g *Generator) envField(t types.Type, v string) (EnvField, bool) {
+	f := EnvField{}
+	if _, named := t.(*types.Named); named {
+		f.Type = types.TypeString(t, g.qualifier)
+	}
+	if named, ok := t.(*types.Named); ok {
+		obj := named.Obj()
+		if obj.Pkg() != nil && obj.Pkg().Path() == "time" && obj.Name() == "Duration" {
+			f.Parse = "time.ParseDuration(" + v + ")"
+			return f, true
+		}
+	}
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		info := u.Info()
+		switch {
+		case info&types.IsString != 0:
+		case info&types.IsBoolean != 0:
+			f.Parse = "strconv.ParseBool(" + v + ")"
+		case info&types.IsUnsigned != 0:
+			f.Parse = fmt.Sprintf("strconv.ParseUint(%s, 10, %d)", v, bitSize(u))
+		case info&types.IsInteger != 0:
+			f.Parse = fmt.Sprintf("strconv.ParseInt(%s, 10, %d)", v, bitSize(u))
+		case info&types.IsFloat != 0:
+			f.Parse = fmt.Sprintf("strconv.ParseFloat(%s, %d)", v, bitSize(u))
+		default:
+			return f, false
+		}
+		if f.Parse != "" {
+			g.addImport("strconv")
+			f.Type = types.TypeString(t, g.qualifier)
+		}
+		return f, true
+	case *types.Slice:
+		if types.Identical(u.Elem(), types.Typ[types.String]) {
+			g.addImport("strings")
+			f.Split = true
+			return f, true
+		}
+	}
+	return f, false
+}
+
+// bitSize is the bitSize strconv should parse a number of type b with.
+func bitSize(b *types.Basic) int {
+	switch b.Kind() {
+	case types.Int8, types.Uint8:
+		return 8
+	case types.Int16, types.Uint16:
+		return 16
+	case types.Int32, types.Uint32, types.Float32:
+		return 32
+	case types.Int64, types.Uint64, types.Float64:
+		return 64
+	}
+	return 0 // int, uint and uintptr
+}
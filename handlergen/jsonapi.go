@@ -0,0 +1,102 @@
+package handlergen
+
+import (
+	"go/types"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// jsonapiMediaType is the Content-Type of the JSON:API documents.
+const jsonapiMediaType = "application/vnd.api+json"
+
+// JSONAPI is the data the jsonapi template is executed with: the names of
+// the types of the JSON:API documents it declares.
+type JSONAPI struct {
+	Document, Resource, Error string
+}
+
+// jsonapiNames returns the names of the types of the JSON:API documents.
+func (g *Generator) jsonapiNames() JSONAPI {
+	return JSONAPI{
+		Document: g.exported("JSONAPIDocument"),
+		Resource: g.exported("JSONAPIResource"),
+		Error:    g.exported("JSONAPIError"),
+	}
+}
+
+// jsonapiDocument returns the JSON:API document the http handler of a func
+// encodes for its resp of type t, a slice or not, along with the code
+// building its data before, if any. It is empty when no resource type can
+// be derived from t.
+func (g *Generator) jsonapiDocument(funcName string, t types.Type, slice bool) (code, document string) {
+	n := g.jsonapiNames()
+	elem := t
+	if slice {
+		elem = t.Underlying().(*types.Slice).Elem()
+	}
+	named, ok := elem.(*types.Named)
+	if p, isPointer := elem.(*types.Pointer); isPointer {
+		named, ok = p.Elem().(*types.Named)
+	}
+	if !ok {
+		g.warnf("cannot derive the JSON:API resource type of the %s responses of %s: they are encoded as is", types.TypeString(t, types.RelativeTo(g.pkg.Types)), funcName)
+		return "", ""
+	}
+	resource := func(v string) string {
+		s := n.Resource + "{Type: " + strconv.Quote(resourceType(named.Obj().Name()))
+		if id := idField(elem); id != "" {
+			g.addImport("fmt")
+			s += ", ID: fmt.Sprint(" + v + "." + id + ")"
+		}
+		return s + ", Attributes: " + v + "}"
+	}
+	if !slice {
+		return "", n.Document + "{Data: " + resource("resp") + "}"
+	}
+	code = "data := make([]" + n.Resource + ", len(resp))\n" +
+		"for i, v := range resp {\n" +
+		"data[i] = " + resource("v") + "\n" +
+		"}"
+	return code, n.Document + "{Data: data}"
+}
+
+// idField returns the name of the ID field of the t struct, like ID or Id,
+// if any.
+func idField(t types.Type) string {
+	for _, name := range []string{"ID", "Id"} {
+		if obj, _, _ := types.LookupFieldOrMethod(t, true, nil, name); obj != nil {
+			if v, ok := obj.(*types.Var); ok && v.IsField() {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// resourceType returns the JSON:API resource type of the values of the
+// name type, in kebab case and plural, like job-runs for JobRun.
+func resourceType(name string) string {
+	var b strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) && (unicode.IsLower(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+			b.WriteByte('-')
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return plural(b.String())
+}
+
+// plural returns the plural of the noun s, like jobs for job or Entries for
+// Entry.
+func plural(s string) string {
+	switch {
+	case strings.HasSuffix(s, "s") || strings.HasSuffix(s, "x") || strings.HasSuffix(s, "z") ||
+		strings.HasSuffix(s, "ch") || strings.HasSuffix(s, "sh"):
+		return s + "es"
+	case len(s) > 1 && strings.HasSuffix(s, "y") && !strings.ContainsRune("aeiou", rune(s[len(s)-2])):
+		return s[:len(s)-1] + "ies"
+	}
+	return s + "s"
+}
@@ -0,0 +1,48 @@
+package handlergen
+
+import "strings"
+
+// Manifest is the data the routes templates declare the manifest of the
+// routes with, with the Manifest option.
+type Manifest struct {
+	Routes string // func returning the RouteInfo of the routes
+	Info   string // type describing a route
+	Split  bool   // the file of each encoding adds the RouteInfo of its routes
+}
+
+// manifestNames returns the Manifest the routes templates are executed
+// with.
+func (g *Generator) manifestNames() *Manifest {
+	return &Manifest{
+		Routes: g.exported("Routes"),
+		Info:   g.exported("RouteInfo"),
+	}
+}
+
+// Method returns the method of the pattern of r, like PUT, empty if it
+// matches every method.
+func (r Route) Method() string {
+	if i := strings.Index(r.Pattern, " "); i >= 0 {
+		return r.Pattern[:i]
+	}
+	return ""
+}
+
+// Path returns the path of the pattern of r, without its method nor host,
+// like /jobs/{id}.
+func (r Route) Path() string {
+	path := r.Pattern
+	if i := strings.Index(path, " "); i >= 0 {
+		path = strings.TrimLeft(path[i+1:], " ")
+	}
+	if i := strings.Index(path, "/"); i > 0 {
+		path = path[i:]
+	}
+	return path
+}
+
+// Name returns the name of the handler func of r, without the receiver of
+// the generated methods.
+func (r Route) Name() string {
+	return strings.TrimPrefix(r.Handler, "s.")
+}
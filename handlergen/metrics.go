@@ -0,0 +1,17 @@
+package handlergen
+
+// Metrics is the data the prometheus templates are executed with, with the
+// Metrics option set to prometheus.
+type Metrics struct {
+	Register string // name of the func registering the metrics
+	Handler  string // name of the handler measuring its requests, if any
+}
+
+// measureRequests returns the code counting and timing the requests of the h
+// http handler once responded, by status class, injected at its top.
+func (g *Generator) measureRequests(h Handler) string {
+	g.addImport("github.com/prometheus/client_golang/prometheus")
+	g.addImport("strconv")
+	g.addImport("time")
+	return g.code("prometheus", Metrics{Handler: h.Name})
+}
@@ -0,0 +1,72 @@
+package handlergen
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// ndjsonTest is the test of the http handler of WatchJob, streaming the
+// values of the chan it returns along a 2xx status, or writing the status
+// alone.
+const ndjsonTest = `package jobs
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNDJSON(t *testing.T) {
+	for _, test := range []struct {
+		id          string
+		status      int
+		body        string
+		contentType string
+	}{
+		{"1", 200, "{\"ID\":\"1\"}\n{\"ID\":\"2\"}\n", "application/x-ndjson"},
+		{"none", 200, "", ""}, // nil
+		{"missing", 404, "", ""},
+		{"down", 503, "", ""},
+	} {
+		w := httptest.NewRecorder()
+		WatchJobHandlerJSON(w, httptest.NewRequest("POST", "/jobs", strings.NewReader("{\"ID\": \""+test.id+"\"}")))
+		if w.Code != test.status || w.Body.String() != test.body || w.Header().Get("Content-Type") != test.contentType {
+			t.Errorf("job %s: responded %d %q, of type %q, want %d %q, of type %q", test.id, w.Code, w.Body, w.Header().Get("Content-Type"), test.status, test.body, test.contentType)
+		}
+	}
+}
+`
+
+func TestNDJSONWithoutStream(t *testing.T) {
+	dir := testModule(t, map[string]string{"jobs.go": `package jobs
+
+type Job struct{ ID string }
+
+func WatchJob(j Job) (<-chan Job, int) {
+	switch j.ID {
+	case "none":
+		return nil, 200
+	case "missing":
+		return nil, 404
+	}
+	values := make(chan Job, 2)
+	values <- Job{ID: "1"}
+	values <- Job{ID: "2"}
+	close(values)
+	if j.ID == "down" {
+		return values, 503
+	}
+	return values, 200
+}
+`})
+	g := &Generator{Stream: "ndjson"}
+	if err := generate(dir, g, []string{"WatchJob"}, "encoding/json"); err != nil {
+		t.Fatal(err)
+	}
+	// Written once generated, not to be type-checked along the package.
+	if err := ioutil.WriteFile(filepath.Join(dir, "ndjson_test.go"), []byte(ndjsonTest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	goTest(t, dir, g)
+}
@@ -0,0 +1,132 @@
+package handlergen
+
+import (
+	"go/types"
+	"mime"
+	"strings"
+)
+
+// Negotiation is the data the negotiate template is executed with.
+type Negotiation struct {
+	Name      string // of the handler negotiating the encoding, like PutJobHandler
+	Receiver  string
+	Encodings []Negotiated
+	Supported string // media types answered, listed in 406 Not Acceptable responses
+	Fail      bool   // 406 responses are responded by Fail, with the Problem, ErrorEncoder, Envelope or JSONAPI option
+	T         string // type of the body, transcoded to the encoding answering, if any
+	Pointer   bool   // the func takes a pointer to T
+}
+
+// Negotiated is an encoding of a Negotiation.
+type Negotiated struct {
+	ContentType string // of the responses
+	MediaType   string // of the Content-Type, like text/xml
+	Handler     string // name of its handler
+	EncodingPkg string // name of the encoding pkg, transcoding the bodies
+
+	encoding string // Import path of the encoding pkg.
+}
+
+// mediaTypes are the media types the encoding pkgs whose adapter has no
+// ContentType answer, when negotiating the encoding.
+var mediaTypes = map[string]string{
+	"encoding/json":                   "application/json",
+	"gopkg.in/yaml.v2":                "application/yaml",
+	"gopkg.in/yaml.v3":                "application/yaml",
+	"github.com/BurntSushi/toml":      "application/toml",
+	"github.com/pelletier/go-toml":    "application/toml",
+	"github.com/pelletier/go-toml/v2": "application/toml",
+	"github.com/ugorji/go/codec":      "application/json",
+}
+
+// contentType returns the Content-Type of the responses encoded with the
+// encoding pkg at path, when negotiating the encoding.
+func (g *Generator) contentType(path string) string {
+	if g.JSONAPI {
+		return jsonapiMediaType
+	}
+	if g.HAL {
+		return halMediaType
+	}
+	if contentType := g.adapter(path).ContentType; contentType != "" {
+		return contentType
+	}
+	return mediaTypes[path]
+}
+
+// buildNegotiation generates the handler of a func calling the handler of
+// the encoding the Accept header of the request tells.
+func (g *Generator) buildNegotiation(funcName, route string, encodings []Negotiated) {
+	n := Negotiation{
+		Name:     g.handlerName(funcName, ""),
+		Receiver: g.receiver(),
+		Fail:     g.failing(),
+	}
+	def := 0 // Answering */* and requests without Accept header.
+	for i, e := range encodings {
+		if e.encoding == g.DefaultEncoding {
+			def = i
+		}
+	}
+	ordered := []Negotiated{encodings[def]}
+	for i, e := range encodings {
+		if i != def {
+			ordered = append(ordered, e)
+		}
+	}
+	seen := make(map[string]bool) // Media types answered.
+	var supported []string
+	for _, e := range ordered {
+		if e.Handler == n.Name {
+			g.errorf("handler name of %s negotiating the encoding is the one of its %s handler", funcName, e.encoding)
+			return
+		}
+		if e.ContentType == "" {
+			g.errorf("cannot negotiate the encoding of %s: no media type known for %s; set the content-type of its adapter", funcName, e.encoding)
+			return
+		}
+		mediaType, _, err := mime.ParseMediaType(e.ContentType)
+		if err != nil {
+			g.errorf("cannot negotiate the encoding of %s: invalid content type of %s: %s", funcName, e.encoding, err)
+			return
+		}
+		if seen[mediaType] {
+			continue // Answered by a previous encoding.
+		}
+		seen[mediaType] = true
+		supported = append(supported, mediaType)
+		e.MediaType = mediaType
+		if g.receiver() != "" {
+			e.Handler = "s." + e.Handler
+		}
+		n.Encodings = append(n.Encodings, e)
+	}
+	n.Supported = strings.Join(supported, ", ")
+
+	g.encodingPkg = ordered[0].EncodingPkg // Encoding the 406 Envelope errors.
+	if n.T = g.request.t; n.T != "" {
+		_, n.Pointer = g.request.body.(*types.Pointer)
+		g.addImport("bytes")
+		g.addImport("io")
+	}
+	g.addImport("mime")
+	g.addImport("strconv")
+	g.addImport("strings")
+	g.execute("negotiate", n)
+	if route != "" {
+		handler := n.Name
+		if g.receiver() != "" {
+			handler = "s." + handler
+		}
+		r := g.request // Of the handler of each encoding.
+		r.contentType = ordered[0].ContentType
+		g.routes = append(g.routes, Route{
+			Pattern:  route,
+			Handler:  handler,
+			Request:  r.t,
+			Response: r.resp,
+			request:  r,
+			fn:       funcName,
+		})
+	}
+}
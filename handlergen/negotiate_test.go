@@ -0,0 +1,108 @@
+package handlergen
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// negotiateTest is the test of the http handlers of GetJob and PutJob
+// negotiating their encoding, JSON by default or XML, PutJob decoding its
+// body with the encoding of its Content-Type.
+const negotiateTest = `package jobs
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	for _, test := range []struct {
+		accept, contentType string
+	}{
+		{"", "application/json"},
+		{"*/*", "application/json"},
+		{"text/xml", "text/xml; charset=utf-8"},
+		{"application/json, text/xml", "application/json"},
+		{"text/xml;q=0.5, application/json;q=0.9", "application/json"},
+		// Explicitly excluded.
+		{"application/json;q=0, */*", "text/xml; charset=utf-8"},
+		{"*/*, application/json;q=0", "text/xml; charset=utf-8"},
+		{"text/*;q=0, */*", "application/json"},
+		{"application/json;q=0, text/xml;q=0, */*", ""},
+		// More specific at equal q.
+		{"*/*, text/xml", "text/xml; charset=utf-8"},
+		{"*/*;q=0.5, text/*;q=0.5", "text/xml; charset=utf-8"},
+		{"application/*, application/json;q=0.5", "application/json"},
+		{"image/png", ""},
+	} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/jobs", nil)
+		if test.accept != "" {
+			r.Header.Set("Accept", test.accept)
+		}
+		GetJobHandler(w, r)
+		got := w.Header().Get("Content-Type")
+		if test.contentType == "" {
+			if w.Code != 406 {
+				t.Errorf("Accept %q: status %d, want 406", test.accept, w.Code)
+			}
+		} else if got != test.contentType {
+			t.Errorf("Accept %q: Content-Type %q, want %q", test.accept, got, test.contentType)
+		}
+	}
+}
+
+func TestNegotiateBody(t *testing.T) {
+	for _, test := range []struct {
+		contentType, body, accept string
+		status                    int
+		resp                      string
+	}{
+		{"", "{\"ID\": \"2\"}", "", 200, "{\"ID\":\"2\"}\n"},
+		{"application/json", "{\"ID\": \"2\"}", "", 200, "{\"ID\":\"2\"}\n"},
+		{"text/xml", "<Job><ID>2</ID></Job>", "application/json", 200, "{\"ID\":\"2\"}\n"},
+		{"text/xml; charset=utf-8", "<Job><ID>2</ID></Job>", "", 200, "{\"ID\":\"2\"}\n"},
+		{"application/json", "{\"ID\": \"2\"}", "text/xml", 200, "<Job><ID>2</ID></Job>"},
+		{"text/xml", "<Job><ID>2</ID></Job>", "text/xml", 200, "<Job><ID>2</ID></Job>"},
+		{"text/xml", "{\"ID\": \"2\"}", "application/json", 400, ""},
+		{"image/png", "{\"ID\": \"2\"}", "application/json", 415, ""},
+	} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("PUT", "/jobs", strings.NewReader(test.body))
+		if test.contentType != "" {
+			r.Header.Set("Content-Type", test.contentType)
+		}
+		if test.accept != "" {
+			r.Header.Set("Accept", test.accept)
+		}
+		PutJobHandler(w, r)
+		if w.Code != test.status {
+			t.Errorf("Content-Type %q, Accept %q: status %d, want %d", test.contentType, test.accept, w.Code, test.status)
+		} else if test.status == 200 && w.Body.String() != test.resp {
+			t.Errorf("Content-Type %q, Accept %q: responded %q, want %q", test.contentType, test.accept, w.Body, test.resp)
+		}
+	}
+}
+`
+
+func TestNegotiate(t *testing.T) {
+	dir := testModule(t, map[string]string{"jobs.go": `package jobs
+
+type Job struct{ ID string }
+
+func GetJob() (Job, int) { return Job{ID: "1"}, 200 }
+
+func PutJob(j Job) (Job, int) { return j, 200 }
+`})
+	g := &Generator{Negotiate: true}
+	if err := generate(dir, g, []string{"GetJob", "PutJob"}, "encoding/json", "encoding/xml"); err != nil {
+		t.Fatal(err)
+	}
+	// Written once generated, not to be type-checked along the package.
+	if err := ioutil.WriteFile(filepath.Join(dir, "negotiate_test.go"), []byte(negotiateTest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	goTest(t, dir, g)
+}
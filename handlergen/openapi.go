@@ -0,0 +1,297 @@
+package handlergen
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/types"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// openAPIVersion is the version of the OpenAPI specs RenderOpenAPI writes.
+const openAPIVersion = "3.0.3"
+
+// request is what the OpenAPI spec, the Postman collection and the manifest
+// tell of the requests of a route.
+type request struct {
+	params      []requestParam // read from the query, path or headers
+	body        types.Type     // decoded from the body, if any
+	contentType string         // of the body, if not the one of the encoding of the route
+	result      types.Type     // of the responses
+	t, resp     string         // types of the body and of the responses, for the manifest
+}
+
+// requestParam is a parameter of a request read from its query, path or
+// headers.
+type requestParam struct {
+	in       string     // query, path or header
+	key      string     // name it is read by, like id
+	t        types.Type // of the parameter, if known
+	optional bool       // it can be left out, like the limit of the page
+}
+
+// Docs is the data the openapi template declares the handlers serving the
+// OpenAPI spec and the Swagger UI with, with the OpenAPI option.
+type Docs struct {
+	Register string // func registering the handlers
+	Routes   string // func registering the routes, like RegisterHandlers
+	Spec     string // const holding the spec
+	Value    string // Go literal of the spec
+}
+
+// RenderOpenAPI writes the OpenAPI 3 spec of the routes of the http handlers
+// Render generates, in JSON: an operation for each, with the query params,
+// path values and headers it reads, the schema of the body it decodes and
+// the one of its responses, the named struct types being components.
+func (g *Generator) RenderOpenAPI(w io.Writer) error {
+	if g.Mode != "" {
+		return fmt.Errorf("cannot describe the %s handlers in an OpenAPI spec", g.Mode)
+	}
+	if err := g.render(ioutil.Discard, output{none: true, routes: "routes_register"}); err != nil {
+		return err
+	}
+	if len(g.routes) == 0 {
+		return errors.New("no handler has a route to describe in an OpenAPI spec")
+	}
+	src, err := g.openAPI(g.routes)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(src, '\n'))
+	return err
+}
+
+// openAPI returns the OpenAPI spec of routes.
+func (g *Generator) openAPI(routes []Route) ([]byte, error) {
+	s := schemas{named: make(map[string]bool)}
+	paths := make(map[string]object)
+	for _, route := range routes {
+		method, path := route.Method(), route.Path()
+		if method == "" && route.request.body != nil {
+			method = "POST"
+		} else if method == "" {
+			method = "GET"
+		}
+		path = strings.Replace(strings.Replace(path, "...}", "}", -1), "{$}", "", -1)
+		paths[path] = append(paths[path], member{strings.ToLower(method), g.openAPIOperation(route, &s)})
+	}
+	spec := object{
+		{"openapi", openAPIVersion},
+		{"info", object{{"title", g.pkg.Name}, {"version", "1.0.0"}}},
+		{"paths", paths},
+	}
+	if len(s.components) > 0 {
+		sort.Slice(s.components, func(i, j int) bool { return s.components[i].name < s.components[j].name })
+		spec = append(spec, member{"components", object{{"schemas", s.components}}})
+	}
+	return json.MarshalIndent(spec, "", "  ")
+}
+
+// openAPIOperation returns the operation of the OpenAPI spec of route,
+// adding the schemas it refers to to s.
+func (g *Generator) openAPIOperation(route Route, s *schemas) object {
+	op := object{{"operationId", route.Name()}}
+	var params []object
+	for _, p := range route.request.params {
+		param := object{{"name", p.key}, {"in", p.in}}
+		if p.in == "path" {
+			param = append(param, member{"required", true})
+		}
+		param = append(param, member{"schema", s.schema(p.t)})
+		params = append(params, param)
+	}
+	if len(params) > 0 {
+		op = append(op, member{"parameters", params})
+	}
+	contentType := g.contentType(route.encoding)
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	if route.request.body != nil {
+		bodyType := route.request.contentType
+		if bodyType == "" {
+			bodyType = contentType
+		}
+		op = append(op, member{"requestBody", object{
+			{"required", true},
+			{"content", object{{bodyType, object{{"schema", s.schema(route.request.body)}}}}},
+		}})
+	}
+	response := object{{"description", "The response of " + route.fn + "."}}
+	if route.request.result != nil {
+		response = append(response, member{"content", object{{contentType, object{{"schema", s.schema(route.request.result)}}}}})
+	}
+	// The func returns the status.
+	return append(op, member{"responses", object{{"default", response}}})
+}
+
+// schemas are the schemas of the components of an OpenAPI spec.
+type schemas struct {
+	components object
+	named      map[string]bool // Names of the components, added or being.
+}
+
+// schema returns the OpenAPI schema of a value of type t encoded in JSON,
+// referring to a component for a named struct type, added to s if needed.
+func (s *schemas) schema(t types.Type) object {
+	if t == nil {
+		return object{}
+	}
+	if named, ok := t.(*types.Named); ok {
+		obj := named.Obj()
+		if obj.Pkg() != nil && obj.Pkg().Path() == "time" && obj.Name() == "Time" {
+			return object{{"type", "string"}, {"format", "date-time"}}
+		}
+		if _, ok := named.Underlying().(*types.Struct); ok {
+			name := componentName(named)
+			if !s.named[name] {
+				s.named[name] = true
+				schema := s.schema(named.Underlying())
+				s.components = append(s.components, member{name, schema})
+			}
+			return object{{"$ref", "#/components/schemas/" + name}}
+		}
+	}
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		switch {
+		case u.Info()&types.IsBoolean != 0:
+			return object{{"type", "boolean"}}
+		case u.Info()&types.IsString != 0:
+			return object{{"type", "string"}}
+		case u.Info()&types.IsInteger != 0:
+			return object{{"type", "integer"}}
+		case u.Info()&types.IsFloat != 0:
+			return object{{"type", "number"}}
+		}
+	case *types.Pointer:
+		return s.schema(u.Elem())
+	case *types.Slice:
+		if b, ok := u.Elem().Underlying().(*types.Basic); ok && b.Kind() == types.Byte {
+			return object{{"type", "string"}, {"format", "byte"}}
+		}
+		return object{{"type", "array"}, {"items", s.schema(u.Elem())}}
+	case *types.Array:
+		return object{{"type", "array"}, {"items", s.schema(u.Elem())}, {"maxItems", u.Len()}, {"minItems", u.Len()}}
+	case *types.Map:
+		return object{{"type", "object"}, {"additionalProperties", s.schema(u.Elem())}}
+	case *types.Struct:
+		return s.structSchema(u)
+	}
+	return object{} // Any value, like an interface{}.
+}
+
+// componentName returns the name of the component of the schema of the
+// named type t, like Page_Job for Page[Job].
+func componentName(t *types.Named) string {
+	name := types.TypeString(t, func(*types.Package) string { return "" })
+	return strings.Trim(strings.Map(func(r rune) rune {
+		if r == '.' || r == '-' || r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return '_'
+	}, name), "_")
+}
+
+// structSchema returns the OpenAPI schema of a struct of type t, with its
+// exported fields named as their json tags tell, promoting the ones of the
+// embedded structs.
+func (s *schemas) structSchema(t *types.Struct) object {
+	var properties object
+	for i := 0; i < t.NumFields(); i++ {
+		field := t.Field(i)
+		tag := strings.Split(reflect.StructTag(t.Tag(i)).Get("json"), ",")
+		name := tag[0]
+		if name == "-" || !field.Exported() && !field.Embedded() {
+			continue
+		}
+		if field.Embedded() && name == "" {
+			ft := field.Type()
+			if p, ok := ft.(*types.Pointer); ok {
+				ft = p.Elem()
+			}
+			if st, ok := ft.Underlying().(*types.Struct); ok {
+				embedded := s.structSchema(st)
+				for _, m := range embedded {
+					if m.name == "properties" {
+						properties = append(properties, m.value.(object)...)
+					}
+				}
+				continue
+			}
+		}
+		if !field.Exported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name()
+		}
+		schema := s.schema(field.Type())
+		for _, option := range tag[1:] {
+			if option == "string" && len(schema) == 1 && schema[0].name == "type" {
+				schema = object{{"type", "string"}} // Quoted.
+			}
+		}
+		properties = append(properties, member{name, schema})
+	}
+	schema := object{{"type", "object"}}
+	if len(properties) > 0 {
+		schema = append(schema, member{"properties", properties})
+	}
+	return schema
+}
+
+// docsNames returns the Docs the openapi template is executed with,
+// holding spec.
+func (g *Generator) docsNames(spec []byte) Docs {
+	value := "`" + string(spec) + "`"
+	if strings.Contains(string(spec), "`") {
+		value = strconv.Quote(string(spec))
+	}
+	return Docs{
+		Register: g.exported("RegisterDocs"),
+		Routes:   g.exported("RegisterHandlers"),
+		Spec:     g.exported("OpenAPISpec"),
+		Value:    value,
+	}
+}
+
+// object is a JSON object keeping the order of its members.
+type object []member
+
+// member is a member of an object.
+type member struct {
+	name  string
+	value interface{}
+}
+
+// MarshalJSON implements json.Marshaler.
+func (o object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, m := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(m.name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(m.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,20 @@
+package handlergen
+
+// traceRequests returns the code starting the span of the h http handler,
+// injected at its top, with the Otel option: a child of the one of the
+// request, like the server span of otelhttp, ended once responded. The
+// handler starts the spans of its steps from the context of the request.
+func (g *Generator) traceRequests(h Handler) string {
+	g.addImport("go.opentelemetry.io/otel/attribute")
+	g.addImport("go.opentelemetry.io/otel/codes")
+	return g.code("otel", struct{ Handler string }{h.Name})
+}
+
+// traceInvalid returns the code recording on its span the err error of a
+// request the http handlers cannot decode.
+func (g *Generator) traceInvalid(err string) string {
+	g.addImport("go.opentelemetry.io/otel/codes")
+	g.addImport("go.opentelemetry.io/otel/trace")
+	return "trace.SpanFromContext(r.Context()).RecordError(" + err + ")\n" +
+		"trace.SpanFromContext(r.Context()).SetStatus(codes.Error, " + err + ".Error())"
+}
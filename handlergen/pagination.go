@@ -0,0 +1,100 @@
+package handlergen
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"strings"
+)
+
+// Pagination is the data the page template is executed with.
+type Pagination struct {
+	Page, Parse, Links string // names of the Page type and of the funcs declared along
+	Size, MaxSize      int    // of the pages
+	Declare            bool   // the Page type is declared along, unless the parsed package declares it
+}
+
+// pagination returns the Pagination declared with the Pagination option.
+func (g *Generator) pagination() Pagination {
+	p := Pagination{
+		Page:    g.exported("Page"),
+		Parse:   g.exported("ParsePage"),
+		Links:   g.exported("PageLinks"),
+		Size:    g.PageSize,
+		MaxSize: g.MaxPageSize,
+	}
+	if p.MaxSize <= 0 {
+		p.MaxSize = 100
+	}
+	if p.Size <= 0 {
+		p.Size = 20
+	}
+	if p.Size > p.MaxSize {
+		p.Size = p.MaxSize
+	}
+	obj := g.pkg.Types.Scope().Lookup(p.Page)
+	p.Declare = obj == nil || g.generated(obj.Pos())
+	return p
+}
+
+// checkPagination checks that the funcs can take the Page type: the one of
+// a previous run, or one the parsed package declares with the int Limit and
+// Offset fields the funcs declared along set.
+func (g *Generator) checkPagination() error {
+	if g.Mode != "" {
+		return fmt.Errorf("cannot paginate the lists of %ss", g.Mode)
+	}
+	if g.Package != "" {
+		return fmt.Errorf("cannot paginate generating into package %s: the funcs cannot take its page", g.Package)
+	}
+	p := g.pagination()
+	if p.Declare {
+		return nil
+	}
+	obj, ok := g.pkg.Types.Scope().Lookup(p.Page).(*types.TypeName)
+	if !ok {
+		return fmt.Errorf("%s is not the type of the pages", p.Page)
+	}
+	for _, field := range []string{"Limit", "Offset"} {
+		v, ok := lookupField(obj.Type(), field)
+		if !ok || !isInt(v.Type()) {
+			return fmt.Errorf("page type %s has no %s int field", p.Page, field)
+		}
+	}
+	return nil
+}
+
+// lookupField returns the field of t named name, if any.
+func lookupField(t types.Type, name string) (*types.Var, bool) {
+	obj, _, _ := types.LookupFieldOrMethod(t, true, nil, name)
+	v, ok := obj.(*types.Var)
+	return v, ok && v.IsField()
+}
+
+// generated reports whether pos is in a generated file of the parsed
+// package, like the output of a previous run.
+func (g *Generator) generated(pos token.Pos) bool {
+	for _, file := range g.pkg.Files {
+		if file.Pos() > pos || pos > file.End() {
+			continue
+		}
+		for _, group := range file.Comments {
+			if group.Pos() > file.Package {
+				break
+			}
+			for _, c := range group.List {
+				if strings.HasPrefix(c.Text, "// Code generated ") && strings.HasSuffix(c.Text, " DO NOT EDIT") {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// isPage reports whether t is the Page type declared with the Pagination
+// option.
+func (g *Generator) isPage(t types.Type) bool {
+	named, ok := t.(*types.Named)
+	return ok && named.Obj().Pkg() == g.pkg.Types && named.Obj().Name() == g.pagination().Page
+}
@@ -0,0 +1,265 @@
+package handlergen
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/types"
+	"io"
+	"io/ioutil"
+	"mime"
+	"reflect"
+	"strings"
+)
+
+// postmanSchema is the schema of the Postman collections RenderPostman
+// writes.
+const postmanSchema = "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+
+// postmanCollection is a Postman collection, in the v2.1 format.
+type postmanCollection struct {
+	Info     postmanInfo       `json:"info"`
+	Item     []postmanItem     `json:"item"`
+	Variable []postmanKeyValue `json:"variable"`
+}
+
+type postmanInfo struct {
+	Name   string `json:"name"`
+	Schema string `json:"schema"`
+}
+
+type postmanItem struct {
+	Name    string         `json:"name"`
+	Request postmanRequest `json:"request"`
+}
+
+type postmanRequest struct {
+	Method string            `json:"method"`
+	Header []postmanKeyValue `json:"header"`
+	URL    postmanURL        `json:"url"`
+	Body   *postmanBody      `json:"body,omitempty"`
+}
+
+type postmanURL struct {
+	Raw      string            `json:"raw"`
+	Host     []string          `json:"host"`
+	Path     []string          `json:"path"`
+	Query    []postmanKeyValue `json:"query,omitempty"`
+	Variable []postmanKeyValue `json:"variable,omitempty"`
+}
+
+type postmanBody struct {
+	Mode    string `json:"mode"`
+	Raw     string `json:"raw"`
+	Options struct {
+		Raw struct {
+			Language string `json:"language"`
+		} `json:"raw"`
+	} `json:"options"`
+}
+
+type postmanKeyValue struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Disabled bool   `json:"disabled,omitempty"`
+}
+
+// RenderPostman writes the Postman collection of the routes of the http
+// handlers Render generates, one request for each, with the query params,
+// path values and headers they read, and an example of the body they
+// decode, synthesized from the type of its parameter when it is JSON. The
+// requests are sent to the baseUrl variable, http://localhost:8080 by
+// default.
+func (g *Generator) RenderPostman(w io.Writer) error {
+	if g.Mode != "" {
+		return fmt.Errorf("cannot export the %s handlers to a Postman collection", g.Mode)
+	}
+	if err := g.render(ioutil.Discard, output{none: true, routes: "routes_register"}); err != nil {
+		return err
+	}
+	if len(g.routes) == 0 {
+		return errors.New("no handler has a route to export to a Postman collection")
+	}
+	c := postmanCollection{
+		Info:     postmanInfo{Name: g.pkg.Name, Schema: postmanSchema},
+		Variable: []postmanKeyValue{{Key: "baseUrl", Value: "http://localhost:8080"}},
+	}
+	for _, route := range g.routes {
+		c.Item = append(c.Item, postmanItem{
+			Name:    route.Name(),
+			Request: g.postmanRequest(route),
+		})
+	}
+	src, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(src, '\n'))
+	return err
+}
+
+// postmanRequest returns the request of the Postman collection of route.
+func (g *Generator) postmanRequest(route Route) postmanRequest {
+	method, path := route.Method(), route.Path()
+	if method == "" && route.request.body != nil {
+		method = "POST"
+	} else if method == "" {
+		method = "GET"
+	}
+	r := postmanRequest{Method: method, Header: []postmanKeyValue{}}
+	r.URL.Host = []string{"{{baseUrl}}"}
+	for _, segment := range strings.Split(strings.Trim(path, "/"), "/") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			name := strings.TrimSuffix(strings.Trim(segment, "{}"), "...")
+			if name == "$" {
+				continue // The end of the path.
+			}
+			segment = ":" + name
+			r.URL.Variable = append(r.URL.Variable, postmanKeyValue{Key: name, Value: paramExample(route.request.params, "path", name)})
+		}
+		r.URL.Path = append(r.URL.Path, segment)
+	}
+	for _, p := range route.request.params {
+		switch p.in {
+		case "query":
+			r.URL.Query = append(r.URL.Query, postmanKeyValue{Key: p.key, Value: exampleString(p.t), Disabled: p.optional})
+		case "header":
+			r.Header = append(r.Header, postmanKeyValue{Key: p.key, Value: exampleString(p.t)})
+		}
+	}
+	r.URL.Raw = "{{baseUrl}}/" + strings.Join(r.URL.Path, "/")
+	if len(r.URL.Query) > 0 {
+		var query []string
+		for _, kv := range r.URL.Query {
+			if !kv.Disabled {
+				query = append(query, kv.Key+"="+kv.Value)
+			}
+		}
+		if len(query) > 0 {
+			r.URL.Raw += "?" + strings.Join(query, "&")
+		}
+	}
+	if route.request.body == nil {
+		return r
+	}
+	contentType := route.request.contentType
+	if contentType == "" {
+		contentType = g.contentType(route.encoding)
+	}
+	r.Body = &postmanBody{Mode: "raw"}
+	r.Body.Options.Raw.Language = "text"
+	if contentType != "" {
+		r.Header = append(r.Header, postmanKeyValue{Key: "Content-Type", Value: contentType})
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if strings.HasSuffix(mediaType, "xml") {
+		r.Body.Options.Raw.Language = "xml"
+	}
+	if mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") {
+		src, err := json.MarshalIndent(example(route.request.body, make(map[*types.Named]bool)), "", "  ")
+		if err != nil {
+			// Should never happen, the examples are made of JSON values.
+			g.warnf("internal error: cannot encode the example body of %s: %s", route.Handler, err)
+			return r
+		}
+		r.Body.Raw = string(src)
+		r.Body.Options.Raw.Language = "json"
+	}
+	return r
+}
+
+// paramExample returns an example of the parameter read from the in source
+// by key, like the path value id, an empty string if none is.
+func paramExample(params []requestParam, in, key string) string {
+	for _, p := range params {
+		if p.in == in && p.key == key {
+			return exampleString(p.t)
+		}
+	}
+	return ""
+}
+
+// exampleString returns an example of a parameter of type t read from a
+// string, like 0 for an int.
+func exampleString(t types.Type) string {
+	if t == nil {
+		return ""
+	}
+	switch v := example(t, make(map[*types.Named]bool)).(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// example returns an example of a value of type t encoded in JSON: the
+// zero value of the basic types, except for strings, time.Time and its
+// RFC 3339 format, and for structs their exported fields, named as their
+// json tags tell. The named types already in seen are null, not to recurse.
+func example(t types.Type, seen map[*types.Named]bool) interface{} {
+	if named, ok := t.(*types.Named); ok {
+		if obj := named.Obj(); obj.Pkg() != nil && obj.Pkg().Path() == "time" && obj.Name() == "Time" {
+			return "2006-01-02T15:04:05Z"
+		}
+		if seen[named] {
+			return nil
+		}
+		seen[named] = true
+		defer delete(seen, named)
+	}
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		switch {
+		case u.Info()&types.IsBoolean != 0:
+			return false
+		case u.Info()&types.IsString != 0:
+			return "string"
+		case u.Info()&types.IsNumeric != 0:
+			return 0
+		}
+	case *types.Pointer:
+		return example(u.Elem(), seen)
+	case *types.Slice:
+		if b, ok := u.Elem().Underlying().(*types.Basic); ok && b.Kind() == types.Byte {
+			return "" // Base64.
+		}
+		return []interface{}{example(u.Elem(), seen)}
+	case *types.Array:
+		return []interface{}{example(u.Elem(), seen)}
+	case *types.Map:
+		if b, ok := u.Key().Underlying().(*types.Basic); ok && b.Info()&types.IsString != 0 {
+			return object{{"key", example(u.Elem(), seen)}}
+		}
+		return object{}
+	case *types.Struct:
+		var o object
+		for i := 0; i < u.NumFields(); i++ {
+			field := u.Field(i)
+			if !field.Exported() && !field.Embedded() {
+				continue
+			}
+			name := strings.SplitN(reflect.StructTag(u.Tag(i)).Get("json"), ",", 2)[0]
+			if name == "-" {
+				continue
+			}
+			if field.Embedded() && name == "" {
+				if embedded, ok := example(field.Type(), seen).(object); ok {
+					o = append(o, embedded...) // Its fields are promoted.
+					continue
+				}
+			}
+			if !field.Exported() {
+				continue
+			}
+			if name == "" {
+				name = field.Name()
+			}
+			o = append(o, member{name, example(field.Type(), seen)})
+		}
+		return o
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package handlergen
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// RateLimit is the data the ratelimit_limiters template is executed with:
+// the limiters of the funcs whose requests are rate limited, like with the
+// RateLimit option.
+type RateLimit struct {
+	Limiters []Limiter
+	Keyed    bool // a limiter is keyed, declaring keyedLimiter
+	IP       bool // a limiter is keyed by client IP, declaring clientIP
+}
+
+// Limiter limits the requests of the http handlers of a func, shared by
+// its encodings, with golang.org/x/time/rate.
+type Limiter struct {
+	Func  string // name of the func limited
+	Var   string // package var of the limiter, like limiterPutJob
+	Limit string // expression of its rate.Limit, like rate.Limit(100) / 60
+	Burst int
+	Key   string // expression of the key of the requests in the handlers, like clientIP(r); none for a global limiter
+}
+
+// parseRateLimit parses a rate limit, requests per s, m or h, optionally
+// followed by the burst: 10/s, or 100/m:20. The burst is the number of
+// requests otherwise.
+func parseRateLimit(s string) (limit string, burst int, err error) {
+	spec, b := s, ""
+	if i := strings.Index(s, ":"); i >= 0 {
+		spec, b = s[:i], s[i+1:]
+	}
+	i := strings.Index(spec, "/")
+	if i < 0 {
+		return "", 0, fmt.Errorf("invalid rate limit %q, want requests/unit like 10/s", s)
+	}
+	n, err := strconv.ParseFloat(spec[:i], 64)
+	if err != nil || n <= 0 || math.IsInf(n, 0) {
+		return "", 0, fmt.Errorf("invalid rate limit %q: want a positive number of requests", s)
+	}
+	limit = "rate.Limit(" + strconv.FormatFloat(n, 'g', -1, 64) + ")"
+	switch spec[i+1:] {
+	case "s":
+	case "m":
+		limit += " / 60"
+	case "h":
+		limit += " / 3600"
+	default:
+		return "", 0, fmt.Errorf("invalid rate limit %q: unknown unit %s, want s, m or h", s, spec[i+1:])
+	}
+	burst = int(math.Ceil(n))
+	if b != "" {
+		burst, err = strconv.Atoi(b)
+		if err != nil || burst < 1 {
+			return "", 0, fmt.Errorf("invalid rate limit %q: want a positive burst", s)
+		}
+	}
+	return limit, burst, nil
+}
+
+// rateLimitKey returns the expression of the key the requests are limited
+// by: none for global, clientIP(r) for ip or the value of the header for
+// header:<name>.
+func rateLimitKey(key string) (string, error) {
+	switch {
+	case key == "" || key == "global":
+		return "", nil
+	case key == "ip":
+		return "clientIP(r)", nil
+	case strings.HasPrefix(key, "header:") && key != "header:":
+		return "r.Header.Get(" + strconv.Quote(strings.TrimPrefix(key, "header:")) + ")", nil
+	}
+	return "", fmt.Errorf("unknown rate limit key %s, want global, ip or header:<name>", key)
+}
+
+// limitRequests returns the code of the h http handler responding 429 Too
+// Many Requests over the rate limit of the func, with the Retry-After
+// header, injected at its top, declaring its limiter once; none if the
+// func is not limited.
+func (g *Generator) limitRequests(h Handler, fn Func, funcName string) string {
+	spec, key := fn.RateLimit, fn.RateLimitKey
+	if spec == "" {
+		spec = g.RateLimit
+	}
+	if key == "" {
+		key = g.RateLimitKey
+	}
+	if spec == "" || spec == "none" {
+		return ""
+	}
+	limit, burst, err := parseRateLimit(spec)
+	if err != nil {
+		g.errorf("%s: %s", funcName, err)
+		return ""
+	}
+	k, err := rateLimitKey(key)
+	if err != nil {
+		g.errorf("%s: %s", funcName, err)
+		return ""
+	}
+	l := Limiter{Func: funcName, Var: "limiter" + funcName, Limit: limit, Burst: burst, Key: k}
+	declared := false
+	for _, d := range g.limiters {
+		declared = declared || d.Var == l.Var
+	}
+	if !declared {
+		g.limiters = append(g.limiters, l)
+	}
+	g.addImport("errors")
+	return g.code("ratelimit", l)
+}
+
+// rateLimit returns the RateLimit the limiters declared are executed with.
+func (g *Generator) rateLimit() RateLimit {
+	r := RateLimit{Limiters: g.limiters}
+	g.addImport("golang.org/x/time/rate")
+	g.addImport("math")
+	g.addImport("strconv")
+	for _, l := range g.limiters {
+		if l.Key != "" {
+			r.Keyed = true
+			g.addImport("sync")
+		}
+		if l.Key == "clientIP(r)" {
+			r.IP = true
+			g.addImport("net")
+		}
+	}
+	return r
+}
@@ -0,0 +1,72 @@
+package handlergen
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// readerTest is the test of the http handler of GetReport, copying the
+// io.Reader it returns, whatever the status, or writing the status alone
+// when it is nil.
+const readerTest = `package jobs
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestReader(t *testing.T) {
+	for _, test := range []struct {
+		id          string
+		status      int
+		body        string
+		contentType string
+	}{
+		{"1", 200, "report 1", "text/csv"},
+		{"gone", 410, "gone", "text/csv"},
+		{"empty", 204, "", ""}, // nil
+		{"missing", 404, "", ""},
+	} {
+		w := httptest.NewRecorder()
+		GetReportHandlerJSON(w, httptest.NewRequest("POST", "/reports", strings.NewReader("{\"ID\": \""+test.id+"\"}")))
+		if w.Code != test.status || w.Body.String() != test.body || w.Header().Get("Content-Type") != test.contentType {
+			t.Errorf("report %s: responded %d %q, of type %q, want %d %q, of type %q", test.id, w.Code, w.Body, w.Header().Get("Content-Type"), test.status, test.body, test.contentType)
+		}
+	}
+}
+`
+
+func TestReaderNil(t *testing.T) {
+	dir := testModule(t, map[string]string{"jobs.go": `package jobs
+
+import (
+	"io"
+	"strings"
+)
+
+type Report struct{ ID string }
+
+func GetReport(r Report) (io.Reader, int) {
+	switch r.ID {
+	case "gone":
+		return strings.NewReader("gone"), 410
+	case "empty":
+		return nil, 204
+	case "missing":
+		return nil, 404
+	}
+	return strings.NewReader("report " + r.ID), 200
+}
+`})
+	g := &Generator{ContentType: "text/csv"}
+	if err := generate(dir, g, []string{"GetReport"}, "encoding/json"); err != nil {
+		t.Fatal(err)
+	}
+	// Written once generated, not to be type-checked along the package.
+	if err := ioutil.WriteFile(filepath.Join(dir, "reader_test.go"), []byte(readerTest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	goTest(t, dir, g)
+}
@@ -0,0 +1,111 @@
+package handlergen
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Requires is the comment telling what the funcs require of the principal
+// of the requests, authenticated with the Auth option, directly above them,
+// as key=value pairs, like a role or permission:
+//
+//	//handler:requires role=admin,owner permission=jobs:write
+//	func DeleteJob(id string) (interface{}, int)
+//
+// Each pair is a requirement the Authorizer checks, met by one of its
+// comma-separated values. They add to the Requires of the Func.
+const Requires = "//handler:requires"
+
+// Requirement is a requirement of a func the Authorizer checks.
+type Requirement struct {
+	Key    string // quoted, like "role"
+	Values string // quoted and comma-separated, like "admin", "owner"
+}
+
+// Authorizer is the data the authorizer template is executed with.
+type Authorizer struct {
+	Authorizer string // interface checking the requirements
+	Var        string // package var of the Authorizer the http handlers use
+	Principal  string // type of who a request is authenticated as
+}
+
+// authorizerNames returns the Authorizer the declarations are executed with.
+func (g *Generator) authorizerNames() Authorizer {
+	return Authorizer{
+		Authorizer: g.exported("Authorizer"),
+		Var:        g.exported("HandlerAuthorizer"),
+		Principal:  g.authNames().Principal,
+	}
+}
+
+// requirements returns the requirements of fn, its Requires along with the
+// ones of its Requires comments, sorted by key.
+func (g *Generator) requirements(fn Func) ([]Requirement, error) {
+	values := make(map[string][]string)
+	for key, value := range fn.Requires {
+		values[key] = append(values[key], strings.Split(value, ",")...)
+	}
+	if d, ok := g.decls[declKey(g.Receiver+g.Interface, fn.Name)]; ok && d.doc != nil {
+		for _, comment := range d.doc.List {
+			if !strings.HasPrefix(comment.Text, Requires+" ") {
+				continue
+			}
+			fields, err := splitOptions(comment.Text[len(Requires):])
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", g.pkg.Fset.Position(comment.Pos()), err)
+			}
+			for _, field := range fields {
+				kv := strings.SplitN(field, "=", 2)
+				if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+					return nil, fmt.Errorf("%s: want key=value, got %s", g.pkg.Fset.Position(comment.Pos()), field)
+				}
+				value := kv[1]
+				if strings.HasPrefix(value, `"`) {
+					if value, err = strconv.Unquote(value); err != nil {
+						return nil, fmt.Errorf("%s: %s: invalid quoted value: %s", g.pkg.Fset.Position(comment.Pos()), kv[0], kv[1])
+					}
+				}
+				values[kv[0]] = append(values[kv[0]], strings.Split(value, ",")...)
+			}
+		}
+	}
+	var keys []string
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var reqs []Requirement
+	for _, key := range keys {
+		var quoted []string
+		for _, v := range values[key] {
+			if v != "" {
+				quoted = append(quoted, strconv.Quote(v))
+			}
+		}
+		reqs = append(reqs, Requirement{Key: strconv.Quote(key), Values: strings.Join(quoted, ", ")})
+	}
+	return reqs, nil
+}
+
+// authorize returns the code of the http handler of fn responding 403
+// Forbidden to the requests whose principal does not meet its
+// requirements, injected at its top after authenticating them; none if it
+// has none.
+func (g *Generator) authorize(fn Func, funcName string, authenticated bool) string {
+	reqs, err := g.requirements(fn)
+	if err != nil {
+		g.errorf("%s", err)
+		return ""
+	}
+	if len(reqs) == 0 {
+		return ""
+	}
+	if !authenticated {
+		g.errorf("%s requires %s of the principal of requests it does not authenticate, see the Auth option", funcName, strings.Trim(reqs[0].Key, `"`))
+		return ""
+	}
+	g.requiring = true
+	return g.code("requires", reqs)
+}
@@ -0,0 +1,91 @@
+package handlergen
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// requiresTest is the test of the http handler of DeleteJob, requiring the
+// role admin or owner: it responds 401 to the requests not authenticated,
+// and 403 to the principals HandlerAuthorizer finds not meeting it, or all
+// until it is set.
+const requiresTest = `package jobs
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type users struct{}
+
+func (users) Authenticate(r *http.Request) (Principal, error) {
+	if user := r.Header.Get("X-User"); user != "" {
+		return user, nil
+	}
+	return nil, errors.New("no user")
+}
+
+// roles maps the users to their role.
+type roles map[string]string
+
+func (roles roles) Authorize(ctx context.Context, p Principal, key string, values []string) (bool, error) {
+	for _, value := range values {
+		if key == "role" && roles[p.(string)] == value {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func TestRequires(t *testing.T) {
+	HandlerAuthenticator = users{}
+	for _, test := range []struct {
+		authorizer Authorizer
+		user       string
+		status     int
+	}{
+		{roles{"ann": "admin"}, "", 401},
+		{nil, "ann", 403},
+		{roles{"ann": "admin", "bob": "reader"}, "bob", 403},
+		{roles{"ann": "admin"}, "ann", 200},
+		{roles{"cat": "owner"}, "cat", 200},
+	} {
+		HandlerAuthorizer = test.authorizer
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/jobs", strings.NewReader("{\"ID\": \"1\"}"))
+		if test.user != "" {
+			r.Header.Set("X-User", test.user)
+		}
+		DeleteJobHandlerJSON(w, r)
+		if w.Code != test.status {
+			t.Errorf("user %q: responded %d, want %d", test.user, w.Code, test.status)
+		}
+	}
+}
+`
+
+func TestRequires(t *testing.T) {
+	dir := testModule(t, map[string]string{"jobs.go": `package jobs
+
+type Job struct{ ID string }
+
+//handler:requires role=admin,owner
+func DeleteJob(j Job) (Job, int) {
+	return j, 200
+}
+`})
+	g := &Generator{Auth: true}
+	if err := generate(dir, g, []string{"DeleteJob"}, "encoding/json"); err != nil {
+		t.Fatal(err)
+	}
+	// Written once generated, not to be type-checked along the package.
+	if err := ioutil.WriteFile(filepath.Join(dir, "requires_test.go"), []byte(requiresTest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	goTest(t, dir, g)
+}
@@ -0,0 +1,95 @@
+package handlergen
+
+import (
+	"errors"
+	"fmt"
+	"go/types"
+	"io"
+)
+
+// Server is the data the server template is executed with.
+type Server struct {
+	Name     string // of the command, like jobs
+	Pkg      string // name the command refers to the parsed package by
+	Register string // func of the package registering the handlers on a mux
+	Health   string // func of the package registering the health handlers, if any
+}
+
+// PackageName returns the name of the parsed package, the one of the
+// RenderServer commands by default.
+func (g *Generator) PackageName() string {
+	if g.pkg == nil {
+		return ""
+	}
+	return g.pkg.Name
+}
+
+// ModuleDir returns the root directory of the module of the parsed package,
+// where RenderServer commands go, under cmd.
+func (g *Generator) ModuleDir() (string, error) {
+	if g.pkg == nil {
+		return "", errors.New("no package parsed")
+	}
+	return g.pkg.ModuleDir()
+}
+
+// RenderServer writes the main.go of the name command serving the handlers
+// the RegisterHandlers of the parsed package, generated by a previous run,
+// registers, and the RegisterHealthHandlers ones if any, with an http.Server
+// shutting down gracefully on SIGINT and SIGTERM.
+func (g *Generator) RenderServer(w io.Writer, name string) error {
+	if g.pkg == nil {
+		return errors.New("no package parsed")
+	}
+	if g.pkg.Name == "main" {
+		return errors.New("cannot serve the handlers of a main package: it cannot be imported")
+	}
+	register := "RegisterHandlers"
+	fn, ok := g.pkg.Types.Scope().Lookup(register).(*types.Func)
+	if !ok || !g.generated(fn.Pos()) {
+		return fmt.Errorf("package %s has no %s: generate its handlers with a route first", g.pkg.Name, register)
+	}
+	sig := fn.Type().(*types.Signature)
+	if sig.Params().Len() != 1 || !isHTTPServeMux(sig.Params().At(0).Type()) {
+		return fmt.Errorf("%s does not take an *http.ServeMux", register)
+	}
+	path, err := g.pkg.ImportPath()
+	if err != nil {
+		return err
+	}
+
+	g.buf.Reset()
+	g.imports = nil
+	g.names = nil
+	g.err = nil
+	g.addImport(path)
+	g.execute("server", Server{
+		Name:     name,
+		Pkg:      g.importName(path, g.pkg.Name),
+		Register: register,
+		Health:   g.healthRegister(),
+	})
+	if g.err != nil {
+		return g.err
+	}
+	code := g.buf.String()
+	g.buf.Reset()
+	g.printGenerated()
+	g.Printf("// Command %s serves the http handlers of %s.\n", name, path)
+	g.Printf("package main\n")
+	g.Printf("\n")
+	g.printImports()
+	g.buf.WriteString(code)
+	src := g.format()
+	if g.err != nil {
+		return g.err
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+// isHTTPServeMux reports whether t is *http.ServeMux.
+func isHTTPServeMux(t types.Type) bool {
+	p, ok := t.(*types.Pointer)
+	return ok && isHTTP(p.Elem(), "ServeMux")
+}
@@ -0,0 +1,67 @@
+package handlergen
+
+import (
+	"errors"
+	"fmt"
+	"go/types"
+)
+
+// Sessions is the data the sessions template is executed with, with the
+// Sessions option.
+type Sessions struct {
+	Session string // type of the sessions the funcs take a pointer to
+	Store   string // interface loading and saving them
+	Var     string // package var of the Store the http handlers use
+	Cookie  string // package var of the name of the cookie holding the ID of the session
+	None    string // error of a request having no session, responded 401 Unauthorized
+	Declare bool   // the Session type is declared along, unless the parsed package declares it
+}
+
+// Session is the *Session parameter of a func, loaded before the body and
+// saved once the func succeeds.
+type Session struct {
+	Var  string // holding the parameter in the handler, like param0
+	None string // error of a request having no session
+	Load string // func loading the session of a request
+	Save string // func saving the session of a request
+}
+
+// sessionNames returns the Sessions the declarations are executed with.
+func (g *Generator) sessionNames() Sessions {
+	s := Sessions{
+		Session: g.exported("Session"),
+		Store:   g.exported("SessionStore"),
+		Var:     g.exported("HandlerSessions"),
+		Cookie:  g.exported("SessionCookie"),
+		None:    g.exported("ErrNoSession"),
+	}
+	obj := g.pkg.Types.Scope().Lookup(s.Session)
+	s.Declare = obj == nil || g.generated(obj.Pos())
+	return s
+}
+
+// checkSessions checks that the funcs can take the Session type: the one
+// of a previous run, or one the parsed package declares.
+func (g *Generator) checkSessions() error {
+	if g.Mode != "" {
+		return fmt.Errorf("cannot load the sessions of %ss", g.Mode)
+	}
+	if g.Package != "" {
+		return fmt.Errorf("cannot load sessions generating into package %s: the funcs cannot take its sessions", g.Package)
+	}
+	s := g.sessionNames()
+	if _, ok := g.pkg.Types.Scope().Lookup(s.Session).(*types.TypeName); !ok && !s.Declare {
+		return errors.New(s.Session + " is not the type of the sessions")
+	}
+	return nil
+}
+
+// isSession reports whether t is a pointer to the Session type.
+func (g *Generator) isSession(t types.Type) bool {
+	p, ok := t.(*types.Pointer)
+	if !ok {
+		return false
+	}
+	named, ok := p.Elem().(*types.Named)
+	return ok && named.Obj().Pkg() == g.pkg.Types && named.Obj().Name() == g.sessionNames().Session
+}
@@ -0,0 +1,108 @@
+package handlergen
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// sessionsTest is the test of the http handler of AddToCart, responding 401
+// to the requests having no session, or all until HandlerSessions is set,
+// and saving the session, the store loading a copy, once the func succeeded
+// alone.
+const sessionsTest = `package jobs
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type store map[string]*Session
+
+func (s store) Load(ctx context.Context, id string) (*Session, error) {
+	if id == "broken" {
+		return nil, errors.New("broken")
+	}
+	session, ok := s[id]
+	if !ok {
+		return nil, nil
+	}
+	values := make(map[string]interface{})
+	for k, v := range session.Values {
+		values[k] = v
+	}
+	return &Session{ID: id, Values: values}, nil
+}
+
+func (s store) Save(ctx context.Context, session *Session) error {
+	s[session.ID] = session
+	return nil
+}
+
+func TestSessions(t *testing.T) {
+	sessions := store{"1": {ID: "1", Values: map[string]interface{}{}}}
+	for _, test := range []struct {
+		store  SessionStore
+		cookie string
+		item   string
+		status int
+		items  int
+	}{
+		{nil, "1", "book", 401, 0},
+		{sessions, "", "book", 401, 0},
+		{sessions, "2", "book", 401, 0},
+		{sessions, "broken", "book", 500, 0},
+		{sessions, "1", "book", 200, 1},
+		{sessions, "1", "", 400, 1},
+		{sessions, "1", "pen", 200, 2},
+	} {
+		HandlerSessions = test.store
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/cart", strings.NewReader("{\"Name\": \""+test.item+"\"}"))
+		if test.cookie != "" {
+			r.AddCookie(&http.Cookie{Name: SessionCookie, Value: test.cookie})
+		}
+		AddToCartHandlerJSON(w, r)
+		if w.Code != test.status {
+			t.Errorf("session %q, item %q: responded %d, want %d", test.cookie, test.item, w.Code, test.status)
+		}
+		if items, _ := sessions["1"].Values["items"].(int); items != test.items {
+			t.Errorf("session %q, item %q: %d items saved, want %d", test.cookie, test.item, items, test.items)
+		}
+	}
+}
+`
+
+func TestSessions(t *testing.T) {
+	dir := testModule(t, map[string]string{"jobs.go": `package jobs
+
+type Session struct {
+	ID     string
+	Values map[string]interface{}
+}
+
+type Item struct{ Name string }
+
+func AddToCart(s *Session, i Item) (int, int) {
+	items, _ := s.Values["items"].(int)
+	s.Values["items"] = items + 1
+	if i.Name == "" {
+		return items, 400
+	}
+	return items + 1, 200
+}
+`})
+	g := &Generator{Sessions: true}
+	if err := generate(dir, g, []string{"AddToCart"}, "encoding/json"); err != nil {
+		t.Fatal(err)
+	}
+	// Written once generated, not to be type-checked along the package.
+	if err := ioutil.WriteFile(filepath.Join(dir, "sessions_test.go"), []byte(sessionsTest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	goTest(t, dir, g)
+}
@@ -0,0 +1,53 @@
+package handlergen
+
+import (
+	"go/types"
+)
+
+// SLog is the data the slog templates are executed with, with the Log
+// option set to slog.
+type SLog struct {
+	Logger  string // package var of the logger, like HandlerLogger
+	Log     string // func returning the logger to log with, the default one if nil
+	Handler string // name of the handler logging its requests, if any
+	Of      string // expression of the logger of the handler, like s.Logger
+}
+
+// slogNames returns the SLog the declarations are executed with.
+func (g *Generator) slogNames() SLog {
+	return SLog{
+		Logger: g.exported("HandlerLogger"),
+		Log:    "handlerLog",
+	}
+}
+
+// logger returns the expression of the logger the http handlers log with:
+// the Logger *slog.Logger field of the Receiver if it has one, the
+// HandlerLogger package var otherwise.
+func (g *Generator) logger() string {
+	n := g.slogNames()
+	if g.Receiver != "" {
+		obj, _, _ := types.LookupFieldOrMethod(g.lookupType(g.Receiver), true, g.pkg.Types, "Logger")
+		if v, ok := obj.(*types.Var); ok && v.IsField() && types.TypeString(v.Type(), nil) == "*log/slog.Logger" {
+			return n.Log + "(s.Logger)"
+		}
+	}
+	return n.Log + "(" + n.Logger + ")"
+}
+
+// logRequests returns the code logging the requests of the h http handler
+// once responded, injected at its top.
+func (g *Generator) logRequests(h Handler) string {
+	g.addImport("log/slog")
+	g.addImport("time")
+	n := g.slogNames()
+	n.Handler, n.Of = h.Name, g.logger()
+	return g.code("slog", n)
+}
+
+// logInvalid returns the code logging the err error of a request the http
+// handlers cannot decode.
+func (g *Generator) logInvalid(err string) string {
+	g.addImport("log/slog")
+	return g.logger() + `.LogAttrs(r.Context(), slog.LevelWarn, "invalid http request", slog.String("method", r.Method), slog.String("path", r.URL.Path), slog.String("error", ` + err + `.Error()))`
+}
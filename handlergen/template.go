@@ -0,0 +1,223 @@
+package handlergen
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// templates holds the default templates, named after their file.
+//
+//go:embed templates/*.gotpl
+var templates embed.FS
+
+// funcMap holds the funcs available to templates.
+//
+//	ToUpper:     strings.ToUpper
+//	Import:      records that the generated code uses the pkg at the given path
+//	Decode:      Decode pkg r v pointer decodes from the io.Reader r into the
+//	             v variable, a pointer or not, with the adapter of the pkg
+//	Encode:      Encode pkg w v slice encodes v, a slice or not, to the
+//	             io.Writer w with the adapter of the pkg
+//	ContentType: the Content-Type of the responses encoded with the pkg, as
+//	             told by its adapter, if any
+//	Fail:        Fail status err responds the status, like
+//	             http.StatusBadRequest, failing with the err error, with the
+//	             Problem option as application/problem+json, with the
+//	             ErrorEncoder one calling it, with the Envelope or JSONAPI
+//	             one encoding its error
+//	Invalid:     Invalid err responds 400 Bad Request to a request that could
+//	             not be decoded, failing with the err error, calling the
+//	             ErrorHandler if set, like Fail otherwise, logging the
+//	             error with the Log option and recording it on the span of
+//	             the request with the Otel one
+//	Call:        Call h resp calls the func of the h Handler, returning into
+//	             the resp variable and status, header and cookies as it
+//	             returns them, in pprof.Do with the PprofLabels option
+func (g *Generator) funcMap() template.FuncMap {
+	return template.FuncMap{
+		"ToUpper": strings.ToUpper,
+		"Import": func(path string) string {
+			g.addImport(path)
+			return ""
+		},
+		"Decode": func(pkg, r, v string, pointer bool) (string, error) {
+			if !pointer {
+				v = "&" + v
+			}
+			return g.adapt(pkg, func(a Adapter) string { return a.Decode }, struct{ Pkg, R, W, V string }{R: r, V: v})
+		},
+		"Encode": func(pkg, w, v string, slice bool) (string, error) {
+			return g.adapt(pkg, func(a Adapter) string {
+				if slice && a.EncodeSlice != "" {
+					return a.EncodeSlice
+				}
+				return a.Encode
+			}, struct{ Pkg, R, W, V string }{W: w, V: v})
+		},
+		"ContentType": func(pkg string) string {
+			return g.adapterOf(pkg).ContentType
+		},
+		"MessageType": g.messageType,
+		"Invalid": func(err string) string {
+			code := ""
+			if g.Log != "" {
+				code = g.logInvalid(err) + "\n"
+			}
+			if g.Otel {
+				code += g.traceInvalid(err) + "\n"
+			}
+			if g.ErrorHandler != "" {
+				return code + g.typeQual() + g.ErrorHandler + "(w, r, http.StatusBadRequest, " + err + ")"
+			}
+			return code + g.fail("http.StatusBadRequest", err)
+		},
+		"Fail": g.fail,
+		"Call": g.call,
+	}
+}
+
+// call returns the code of the h http handler calling the func, returning
+// into resp, the status along, with the PprofLabels option in pprof.Do.
+func (g *Generator) call(h Handler, resp string) string {
+	vars := []string{resp, "status"}
+	types := []string{h.Resp, "int"}
+	if h.StatusFirst {
+		vars[0], vars[1] = vars[1], vars[0]
+		types[0], types[1] = types[1], types[0]
+	}
+	if h.Header {
+		vars = append(vars, "header")
+		types = append(types, "http.Header")
+	}
+	if h.Cookie {
+		vars = append(vars, "cookies")
+		types = append(types, "[]*http.Cookie")
+	}
+	call := h.Qual + h.Func + "(" + h.Args + ")"
+	if h.Labels == "" {
+		return strings.Join(vars, ", ") + " := " + call
+	}
+	g.addImport("context")
+	g.addImport("runtime/pprof")
+	code := ""
+	for i, v := range vars {
+		code += "var " + v + " " + types[i] + "\n"
+	}
+	return code + "pprof.Do(r.Context(), pprof.Labels(" + h.Labels + "), func(context.Context) {\n" +
+		strings.Join(vars, ", ") + " = " + call + "\n" +
+		"})"
+}
+
+// failing reports whether Fail writes the errors of the http handlers.
+func (g *Generator) failing() bool {
+	return g.Problem || g.ErrorEncoder != "" || g.Envelope || g.JSONAPI
+}
+
+// fail returns the code of the http handlers responding the status, failing
+// with the err error.
+func (g *Generator) fail(status, err string) string {
+	if g.ErrorEncoder != "" {
+		return g.typeQual() + g.ErrorEncoder + "(w, r, " + status + ", " + err + ")"
+	}
+	if g.JSONAPI && !g.Problem {
+		n := g.jsonapiNames()
+		g.addImport("strconv")
+		v := n.Document + "{Errors: []" + n.Error + "{{Status: strconv.Itoa(" + status + "), Title: http.StatusText(" + status + "), Detail: " + err + ".Error()}}}"
+		encode, e := g.adapt(g.encodingPkg, func(a Adapter) string { return a.Encode }, struct{ Pkg, R, W, V string }{W: "w", V: v})
+		if e != nil {
+			g.errorf("%s", e)
+		}
+		return "w.Header().Set(\"Content-Type\", \"" + jsonapiMediaType + "\")\nw.WriteHeader(" + status + ")\n" + encode
+	}
+	if g.Envelope && !g.Problem {
+		v := g.exported("Envelope") + "{Error: &" + g.exported("EnvelopeError") + "{Status: " + status + ", Title: http.StatusText(" + status + "), Detail: " + err + ".Error()}}"
+		encode, e := g.adapt(g.encodingPkg, func(a Adapter) string { return a.Encode }, struct{ Pkg, R, W, V string }{W: "w", V: v})
+		if e != nil {
+			g.errorf("%s", e)
+		}
+		return "w.WriteHeader(" + status + ")\n" + encode
+	}
+	if !g.Problem {
+		return "w.WriteHeader(" + status + ")"
+	}
+	g.addImport("encoding/json")
+	return g.code("problem", struct{ JSON, Status, Err string }{g.importName("encoding/json", "json"), status, err})
+}
+
+// execute executes the name template with data into the buffer.
+func (g *Generator) execute(name string, data interface{}) {
+	g.Printf("\n")
+	g.executeTo(&g.buf, name, data)
+}
+
+// code returns the code the name template executes to with data, to be
+// injected in the one of another template.
+func (g *Generator) code(name string, data interface{}) string {
+	var buf bytes.Buffer
+	g.executeTo(&buf, name, data)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// executeTo executes the name template with data into w.
+func (g *Generator) executeTo(w io.Writer, name string, data interface{}) {
+	t, ok := g.templates[name]
+	if !ok {
+		var err error
+		t, err = g.loadTemplate(name)
+		if err != nil {
+			g.errorf("%s", err)
+			return
+		}
+	}
+	g.logf("executing template %s", name)
+	err := t.Execute(w, data)
+	if err != nil {
+		g.errorf("executing template %s: %s", name, err)
+	}
+}
+
+// loadTemplate parses the name template from TemplateDir if it is there,
+// from the embedded ones otherwise.
+func (g *Generator) loadTemplate(name string) (*template.Template, error) {
+	if g.TemplateDir != "" {
+		file := filepath.Join(g.TemplateDir, name+".gotpl")
+		if _, err := os.Stat(file); err == nil {
+			g.logf("template %s read from %s", name, file)
+			return g.parseTemplate(name, file)
+		}
+	}
+	text, err := templates.ReadFile("templates/" + name + ".gotpl")
+	if err != nil {
+		return nil, err
+	}
+	return g.addTemplate(name, string(text))
+}
+
+// parseTemplate parses file as the name template.
+func (g *Generator) parseTemplate(name, file string) (*template.Template, error) {
+	text, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read template: %s", err)
+	}
+	return g.addTemplate(name, string(text))
+}
+
+// addTemplate parses text as the name template.
+func (g *Generator) addTemplate(name, text string) (*template.Template, error) {
+	t, err := template.New(name).Funcs(g.funcMap()).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse template %s: %s", name, err)
+	}
+	if g.templates == nil {
+		g.templates = make(map[string]*template.Template)
+	}
+	g.templates[name] = t
+	return t, nil
+}
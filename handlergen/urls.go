@@ -0,0 +1,125 @@
+package handlergen
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"strings"
+)
+
+// URLBuilder is the data the urls template declares a func building the
+// paths of a route with, with the URLs option.
+type URLBuilder struct {
+	Name    string // of the func, like PutJobURL
+	Func    string // whose route it is, like PutJob
+	Pattern string // of the route, like PUT /jobs/{id}
+	Params  string // of the func, like id string
+	Path    string // expression of the path, like "/jobs/" + url.PathEscape(id)
+}
+
+// urlBuilders returns the URLBuilder of each of routes, taking its
+// wildcards of the type of the parameters of the func read from them,
+// string by default.
+func (g *Generator) urlBuilders(routes []Route) []URLBuilder {
+	var builders []URLBuilder
+	for _, route := range routes {
+		b := URLBuilder{
+			Name:    g.exported(route.fn + "URL"),
+			Func:    route.fn,
+			Pattern: route.Pattern,
+		}
+		var params, parts []string
+		literal := ""
+		for _, segment := range strings.SplitAfter(route.Path(), "/") {
+			wildcard := strings.TrimSuffix(segment, "/")
+			if !strings.HasPrefix(wildcard, "{") || !strings.HasSuffix(wildcard, "}") {
+				literal += segment
+				continue
+			}
+			name := strings.Trim(wildcard, "{}")
+			if name == "$" {
+				continue // The end of the path, after its slash.
+			}
+			if literal != "" {
+				parts = append(parts, fmt.Sprintf("%q", literal))
+			}
+			literal = strings.TrimPrefix(segment, wildcard)
+			rest := strings.HasSuffix(name, "...")
+			name = strings.TrimSuffix(name, "...")
+			var t types.Type = types.Typ[types.String]
+			for _, p := range route.request.params {
+				if p.in == "path" && p.key == name {
+					t = p.t
+				}
+			}
+			arg := name
+			if token.IsKeyword(arg) || arg == "url" || arg == "strconv" {
+				arg += "Value"
+			}
+			params = append(params, arg+" "+types.TypeString(t, g.qualifier))
+			u, _ := t.Underlying().(*types.Basic)
+			switch {
+			case u == nil || u.Info()&types.IsString == 0:
+				parts = append(parts, g.formatParam(t, arg)) // Nothing to escape.
+			case rest:
+				// Keeping the slashes between the segments it matches.
+				g.addImport("net/url")
+				parts = append(parts, "(&url.URL{Path: "+g.formatParam(t, arg)+"}).EscapedPath()")
+			default:
+				g.addImport("net/url")
+				parts = append(parts, "url.PathEscape("+g.formatParam(t, arg)+")")
+			}
+		}
+		if literal != "" || len(parts) == 0 {
+			parts = append(parts, fmt.Sprintf("%q", literal))
+		}
+		b.Params = strings.Join(params, ", ")
+		b.Path = strings.Join(parts, " + ")
+		builders = append(builders, b)
+	}
+	return builders
+}
+
+// formatParam returns the expression formatting v, a parameter of type t,
+// as a string the http handlers parse back, like strconv.FormatInt(v, 10)
+// for an int64.
+func (g *Generator) formatParam(t types.Type, v string) string {
+	if named, ok := t.(*types.Named); ok {
+		if obj := named.Obj(); obj.Pkg() != nil && obj.Pkg().Path() == "time" && obj.Name() == "Duration" {
+			return v + ".String()"
+		}
+	}
+	u, ok := t.Underlying().(*types.Basic)
+	if !ok {
+		return v
+	}
+	// conv converts v to the type of kind the formatting func takes.
+	conv := func(kind types.BasicKind) string {
+		if types.Identical(t, types.Typ[kind]) {
+			return v
+		}
+		return types.Typ[kind].Name() + "(" + v + ")"
+	}
+	info := u.Info()
+	switch {
+	case info&types.IsString != 0:
+		return conv(types.String)
+	case info&types.IsBoolean != 0:
+		g.addImport("strconv")
+		return "strconv.FormatBool(" + conv(types.Bool) + ")"
+	case info&types.IsUnsigned != 0:
+		g.addImport("strconv")
+		return "strconv.FormatUint(" + conv(types.Uint64) + ", 10)"
+	case info&types.IsInteger != 0:
+		g.addImport("strconv")
+		return "strconv.FormatInt(" + conv(types.Int64) + ", 10)"
+	case info&types.IsFloat != 0:
+		size := bitSize(u)
+		if size == 0 {
+			size = 64
+		}
+		g.addImport("strconv")
+		return fmt.Sprintf("strconv.FormatFloat(%s, 'g', -1, %d)", conv(types.Float64), size)
+	}
+	return v
+}
@@ -0,0 +1,49 @@
+package handlergen
+
+import (
+	"errors"
+	"mime"
+	"strings"
+)
+
+// messageType returns the type of the WebSocket messages carrying the
+// responses encoded with the encoding pkg the generated code refers to by
+// pkg: text ones for the textual media types, like application/json, for
+// the browsers to receive strings, and binary ones for the others.
+func (g *Generator) messageType(pkg string) string {
+	contentType := g.adapterOf(pkg).ContentType
+	for path, n := range g.names {
+		if n.ident() == pkg && contentType == "" {
+			contentType = mediaTypes[path]
+		}
+	}
+	mediaType, params, _ := mime.ParseMediaType(contentType)
+	switch {
+	case params["charset"] != "",
+		strings.HasPrefix(mediaType, "text/"),
+		strings.HasSuffix(mediaType, "+json"),
+		strings.HasSuffix(mediaType, "+xml"),
+		mediaType == "application/json",
+		mediaType == "application/xml",
+		mediaType == "application/yaml",
+		mediaType == "application/toml":
+		return "websocket.TextMessage"
+	}
+	return "websocket.BinaryMessage"
+}
+
+// checkWebSocket returns an error if an option the http handler h applies
+// cannot apply to its WebSocket endpoint: logging and measuring the requests
+// wrap the http.ResponseWriter, which the upgrade then cannot hijack, and a
+// connection has no response to replay.
+func checkWebSocket(h Handler) error {
+	switch {
+	case h.Log != "":
+		return errors.New("cannot log the requests of its WebSocket endpoint")
+	case h.Metrics != "":
+		return errors.New("cannot measure the requests of its WebSocket endpoint")
+	case h.Idempotency != "":
+		return errors.New("cannot replay the responses of its WebSocket endpoint")
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package handlergen
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// webSocketTest is the test of the WebSocket endpoints of PutJob, each
+// sending the Job it answers in a message of the type its encoding tells,
+// and rate limiting the connections, two an hour, as its http handlers.
+const webSocketTest = `package jobs
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestWebSocket(t *testing.T) {
+	for _, test := range []struct {
+		handler     http.HandlerFunc
+		messageType int
+		encode      func(*bytes.Buffer, Job) error
+		decode      func([]byte, *Job) error
+	}{{
+		PutJobWebSocketJSON, websocket.TextMessage,
+		func(w *bytes.Buffer, j Job) error { return json.NewEncoder(w).Encode(j) },
+		func(b []byte, j *Job) error { return json.Unmarshal(b, j) },
+	}, {
+		PutJobWebSocketGOB, websocket.BinaryMessage,
+		func(w *bytes.Buffer, j Job) error { return gob.NewEncoder(w).Encode(j) },
+		func(b []byte, j *Job) error { return gob.NewDecoder(bytes.NewReader(b)).Decode(j) },
+	}} {
+		s := httptest.NewServer(test.handler)
+		url := "ws" + strings.TrimPrefix(s.URL, "http")
+		conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var frame bytes.Buffer
+		if err := test.encode(&frame, Job{ID: "1"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := conn.WriteMessage(websocket.BinaryMessage, frame.Bytes()); err != nil {
+			t.Fatal(err)
+		}
+		messageType, msg, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if messageType != test.messageType {
+			t.Errorf("message type %d, want %d", messageType, test.messageType)
+		}
+		var got Job
+		if err := test.decode(msg, &got); err != nil {
+			t.Fatal(err)
+		}
+		if want := (Job{ID: "1", Done: true}); got != want {
+			t.Errorf("response %+v, want %+v", got, want)
+		}
+		conn.Close()
+		s.Close()
+	}
+	// The endpoints share the limiter of PutJob.
+	s := httptest.NewServer(http.HandlerFunc(PutJobWebSocketJSON))
+	defer s.Close()
+	if _, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(s.URL, "http"), nil); err == nil || resp == nil || resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("connecting over the rate limit: %v, want 429 Too Many Requests", err)
+	}
+}
+`
+
+func TestWebSocket(t *testing.T) {
+	dir := testModule(t, map[string]string{"jobs.go": jobs},
+		"github.com/gorilla/websocket v1.5.3", "golang.org/x/time v0.5.0")
+	g := &Generator{WebSocket: true, RateLimit: "1/h:2"}
+	if err := generate(dir, g, []string{"PutJob"}, "encoding/json", "encoding/gob"); err != nil {
+		t.Fatal(err)
+	}
+	// Written once generated, not to be type-checked along the package.
+	if err := ioutil.WriteFile(filepath.Join(dir, "websocket_test.go"), []byte(webSocketTest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	goTest(t, dir, g)
+}
+
+func TestWebSocketRegenerate(t *testing.T) {
+	dir := testModule(t, map[string]string{"jobs.go": jobs}, "github.com/gorilla/websocket v1.5.3")
+	for i := 0; i < 2; i++ {
+		// The second time, over the output importing gorilla/websocket.
+		if err := generate(dir, &Generator{WebSocket: true}, []string{"PutJob"}, "encoding/json"); err != nil {
+			t.Fatalf("generating %d: %s", i+1, err)
+		}
+	}
+}
+
+func TestWebSocketWithoutResponseWriter(t *testing.T) {
+	dir := testModule(t, map[string]string{"jobs.go": jobs})
+	for _, test := range []struct {
+		g   *Generator
+		err string
+	}{
+		{&Generator{WebSocket: true, Log: "slog"}, "PutJob cannot log the requests of its WebSocket endpoint"},
+		{&Generator{WebSocket: true, Metrics: "prometheus"}, "PutJob cannot measure the requests of its WebSocket endpoint"},
+		{&Generator{WebSocket: true, Idempotency: true}, "PutJob cannot replay the responses of its WebSocket endpoint"},
+	} {
+		err := generate(dir, test.g, []string{"PutJob"}, "encoding/json")
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("generating returns %v, want %s", err, test.err)
+		}
+	}
+}
@@ -0,0 +1,326 @@
+// Package loader parses and type-checks the package a generator generates
+// code for. It is shared by handlergen and varhandlergen.
+package loader // import "github.com/azr/generators/loader"
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/azr/generators/utils"
+)
+
+// Package is a parsed and type-checked package.
+type Package struct {
+	Dir   string                      // Directory of the package.
+	Name  string                      // Name of the package.
+	Fset  *token.FileSet              // Positions of Files.
+	Files []*ast.File                 // Parsed AST of each file.
+	Defs  map[*ast.Ident]types.Object // Objects defined by identifiers.
+	Types *types.Package              // Type-checked package.
+}
+
+// cache holds the packages loaded, once Cache is called.
+var cache struct {
+	sync.Mutex
+	importer types.Importer           // Shared by the loads, to import each package once.
+	exports  map[string]string        // Export data files the importer reads, by import path.
+	pkgs     map[string]cachedPackage // By working directory, directory and files.
+}
+
+// cachedPackage is a package loaded from files of the stamp.
+type cachedPackage struct {
+	stamp string
+	pkg   *Package
+}
+
+// Cache makes the later loads reuse the packages loaded from the same files,
+// as long as the files are unchanged, and share the packages they import.
+// It keeps them warm in a long-running command, like handler serve, whose
+// loads must not be concurrent; the imported packages are read only once.
+func Cache() {
+	cache.Lock()
+	defer cache.Unlock()
+	if cache.importer == nil {
+		cache.exports = make(map[string]string)
+		cache.importer = importer.ForCompiler(token.NewFileSet(), "gc", func(path string) (io.ReadCloser, error) {
+			cache.Lock()
+			file, ok := cache.exports[path]
+			cache.Unlock()
+			return openExport(path, file, ok)
+		})
+		cache.pkgs = make(map[string]cachedPackage)
+	}
+}
+
+// sharedImporter returns the importer shared by the loads, or nil if Cache
+// was not called.
+func sharedImporter() types.Importer {
+	cache.Lock()
+	defer cache.Unlock()
+	return cache.importer
+}
+
+// stamp returns the sizes and modification times of the named files, which
+// change along them.
+func stamp(names []string) string {
+	var b strings.Builder
+	for _, name := range names {
+		info, err := os.Stat(name)
+		if err != nil {
+			return "" // Not to be cached.
+		}
+		fmt.Fprintf(&b, "%s %d %d\n", name, info.Size(), info.ModTime().UnixNano())
+	}
+	return b.String()
+}
+
+// Load parses and type-checks either one directory or a list of files of a
+// single package. Default is the current directory.
+func Load(args ...string) (*Package, error) {
+	if len(args) == 0 {
+		args = []string{"."}
+	}
+	if len(args) == 1 && utils.IsDirectory(args[0]) {
+		return LoadDir(args[0])
+	}
+	return LoadFiles(args)
+}
+
+// Dir returns the directory of the package Load(args...) loads, where
+// generated files go by default.
+func Dir(args ...string) string {
+	if len(args) == 0 {
+		return "."
+	}
+	if len(args) == 1 && utils.IsDirectory(args[0]) {
+		return args[0]
+	}
+	return filepath.Dir(args[0])
+}
+
+// LoadDir parses the package residing in the directory.
+func LoadDir(directory string) (*Package, error) {
+	pkg, err := build.Default.ImportDir(directory, 0)
+	if err != nil {
+		return nil, fmt.Errorf("cannot process directory %s: %s", directory, err)
+	}
+	var names []string
+	names = append(names, pkg.GoFiles...)
+	names = append(names, pkg.CgoFiles...)
+	names = append(names, pkg.SFiles...)
+	names = prefixDirectory(directory, names)
+	return parsePackage(directory, names, nil, false)
+}
+
+// LoadTestDir is like LoadDir, but also parses the _test.go files of the
+// package: the ones of the package itself, or, if external, the ones of its
+// external test package, like foo_test, alone.
+func LoadTestDir(directory string, external bool) (*Package, error) {
+	pkg, err := build.Default.ImportDir(directory, 0)
+	if err != nil {
+		return nil, fmt.Errorf("cannot process directory %s: %s", directory, err)
+	}
+	var names []string
+	if external {
+		if len(pkg.XTestGoFiles) == 0 {
+			return nil, fmt.Errorf("%s: no external test files", directory)
+		}
+		names = append(names, pkg.XTestGoFiles...)
+	} else {
+		names = append(names, pkg.GoFiles...)
+		names = append(names, pkg.CgoFiles...)
+		names = append(names, pkg.TestGoFiles...) // These are also in the "foo" package.
+		names = append(names, pkg.SFiles...)
+	}
+	names = prefixDirectory(directory, names)
+	// The package an external test package tests is imported from source.
+	return parsePackage(directory, names, nil, external)
+}
+
+// LoadFiles parses the package occupying the named files.
+func LoadFiles(names []string) (*Package, error) {
+	return parsePackage(".", names, nil, false)
+}
+
+// prefixDirectory places the directory name on the beginning of each name in the list.
+func prefixDirectory(directory string, names []string) []string {
+	if directory == "." {
+		return names
+	}
+	ret := make([]string, len(names))
+	for i, name := range names {
+		ret[i] = filepath.Join(directory, name)
+	}
+	return ret
+}
+
+// parsePackage analyzes the single package constructed from the named files.
+// If text is non-nil, it is a string to be used instead of the content of the file,
+// to be used for testing. With fromSource, imported packages are type-checked
+// from source rather than read from export data.
+func parsePackage(directory string, names []string, text interface{}, fromSource bool) (*Package, error) {
+	if sharedImporter() != nil && text == nil {
+		// The paths of the package, like its Dir, are relative to the
+		// working directory.
+		wd, _ := os.Getwd()
+		key := fmt.Sprint(wd, directory, names, fromSource)
+		st := stamp(names)
+		cache.Lock()
+		c, ok := cache.pkgs[key]
+		cache.Unlock()
+		if ok && st != "" && c.stamp == st {
+			return c.pkg, nil
+		}
+		pkg, err := parseFiles(directory, names, text, fromSource)
+		if err == nil && st != "" {
+			cache.Lock()
+			cache.pkgs[key] = cachedPackage{st, pkg}
+			cache.Unlock()
+		}
+		return pkg, err
+	}
+	return parseFiles(directory, names, text, fromSource)
+}
+
+// parseFiles is parsePackage without the cache.
+func parseFiles(directory string, names []string, text interface{}, fromSource bool) (*Package, error) {
+	pkg := &Package{
+		Dir:  directory,
+		Fset: token.NewFileSet(),
+	}
+	var goFiles []string
+	for _, name := range names {
+		if strings.HasSuffix(name, ".go") {
+			goFiles = append(goFiles, name)
+		}
+	}
+	// Parse the files concurrently, keeping them in order.
+	files := make([]*ast.File, len(goFiles))
+	errs := make([]error, len(goFiles))
+	utils.Parallel(len(goFiles), func(i int) {
+		files[i], errs[i] = parser.ParseFile(pkg.Fset, goFiles[i], text, parser.ParseComments)
+	})
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("parsing package: %s: %s", goFiles[i], err)
+		}
+	}
+	pkg.Files = files
+	if len(pkg.Files) == 0 {
+		return nil, fmt.Errorf("%s: no buildable Go files", directory)
+	}
+	pkg.Name = pkg.Files[0].Name.Name
+	// Type check the package.
+	return pkg, pkg.check(fromSource)
+}
+
+// check type-checks the package. The package must be OK to proceed.
+func (pkg *Package) check(fromSource bool) error {
+	pkg.Defs = make(map[*ast.Ident]types.Object)
+	config := types.Config{
+		FakeImportC: true,
+		Importer:    importer.Default(),
+	}
+	if fromSource {
+		config.Importer = importer.ForCompiler(pkg.Fset, "source", nil)
+	} else if exports, err := pkg.exports(); err == nil {
+		if imp := sharedImporter(); imp != nil {
+			cache.Lock()
+			for path, file := range exports {
+				cache.exports[path] = file
+			}
+			cache.Unlock()
+			config.Importer = imp
+		} else {
+			config.Importer = importer.ForCompiler(pkg.Fset, "gc", func(path string) (io.ReadCloser, error) {
+				file, ok := exports[path]
+				return openExport(path, file, ok)
+			})
+		}
+	}
+	info := &types.Info{
+		Defs: pkg.Defs,
+	}
+	typesPkg, err := config.Check(pkg.Dir, pkg.Fset, pkg.Files, info)
+	if err != nil {
+		return fmt.Errorf("checking package: %s", err)
+	}
+	pkg.Types = typesPkg
+	return nil
+}
+
+// exports returns the export data files of the packages the files of the
+// package import, by import path, as told by go list: they are found in the
+// modules the one of the package requires, like the ones the generated code
+// imports, which the default importer only looks for in GOPATH.
+func (pkg *Package) exports() (map[string]string, error) {
+	args := []string{"list", "-e", "-export", "-f", "{{.ImportPath}}\t{{.Export}}"}
+	seen := make(map[string]bool)
+	for _, file := range pkg.Files {
+		for _, spec := range file.Imports {
+			path := strings.Trim(spec.Path.Value, "`\"")
+			if path != "C" && path != "unsafe" && !seen[path] {
+				seen[path] = true
+				args = append(args, path)
+			}
+		}
+	}
+	exports := make(map[string]string)
+	if len(seen) == 0 {
+		return exports, nil
+	}
+	cmd := exec.Command("go", args...)
+	cmd.Dir = pkg.Dir
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("finding the packages %s imports: %s", pkg.Dir, err)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if i := strings.Index(line, "\t"); i >= 0 && i < len(line)-1 {
+			exports[line[:i]] = line[i+1:]
+		}
+	}
+	return exports, nil
+}
+
+// openExport opens the export data file of the package of path, if found.
+func openExport(path, file string, found bool) (io.ReadCloser, error) {
+	if !found {
+		return nil, fmt.Errorf("no export data for %s", path)
+	}
+	return os.Open(file)
+}
+
+// ImportPath returns the import path of the package, as told by go list.
+func (pkg *Package) ImportPath() (string, error) {
+	cmd := exec.Command("go", "list", "-find", "-f", "{{.ImportPath}}", ".")
+	cmd.Dir = pkg.Dir
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("finding the import path of %s: %s", pkg.Dir, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// ModuleDir returns the root directory of the module of the package, as
+// told by go list.
+func (pkg *Package) ModuleDir() (string, error) {
+	cmd := exec.Command("go", "list", "-m", "-f", "{{.Dir}}")
+	cmd.Dir = pkg.Dir
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("finding the module of %s: %s", pkg.Dir, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
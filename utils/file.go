@@ -13,9 +13,10 @@ import (
 //
 //it's to update when the system pkg implements CopyFile
 
+// Flags of WriteFile and CopyFile.
 const (
-	writeExec = 1 << iota
-	writeSkipSame
+	WriteExec     = 1 << iota // Mark the file as executable.
+	WriteSkipSame             // Do not rewrite a file that has the content.
 )
 
 // ReadFile returns the content of the named file.
@@ -33,14 +34,14 @@ func ReadFile(file string) string {
 // it is not rewritten, to avoid changing the time stamp.
 func WriteFile(b, file string, flag int) {
 	new := []byte(b)
-	if flag&writeSkipSame != 0 {
+	if flag&WriteSkipSame != 0 {
 		old, err := ioutil.ReadFile(file)
 		if err == nil && bytes.Equal(old, new) {
 			return
 		}
 	}
 	mode := os.FileMode(0666)
-	if flag&writeExec != 0 {
+	if flag&WriteExec != 0 {
 		mode = 0777
 	}
 	err := ioutil.WriteFile(file, new, mode)
@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"runtime"
+	"sync"
+)
+
+// Parallel calls fn for each i in [0, n), at most GOMAXPROCS at once, and
+// returns once they all returned. Callers keep their results ordered by
+// writing each one at its index.
+func Parallel(n int, fn func(i int)) {
+	workers := runtime.GOMAXPROCS(0)
+	if workers > n {
+		workers = n
+	}
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+}
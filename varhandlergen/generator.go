@@ -0,0 +1,272 @@
+// Package varhandlergen generates http handlers calling funcs taking any
+// number of parameters, each one instantiated from the request.
+//
+// It is what the varhandler command runs, see
+// http://godoc.org/github.com/azr/generators/varhandler for what is generated.
+// Build tools can use it without shelling out:
+//
+//  g := varhandlergen.Generator{By: "mytool"}
+//  g.AddFunc("F")
+//  err := g.Parse("./server")
+//  ...
+//  err = g.Render(w)
+//  ...
+//  err = g.WriteHelpers("./server")
+package varhandlergen // import "github.com/azr/generators/varhandlergen"
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"io"
+	"log"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"text/template"
+
+	"github.com/azr/generators/loader"
+	"github.com/azr/generators/utils"
+)
+
+// Generator holds the state of the analysis. Primarily used to buffer
+// the output for format.Source.
+type Generator struct {
+	// By is credited in the "Code generated by" header.
+	// Default is varhandlergen.
+	By string
+
+	// Version of the tool credited along By, like v1.2.0, if known.
+	Version string
+
+	buf   bytes.Buffer    // Accumulated output.
+	pkg   *loader.Package // Package we are scanning.
+	files []*File         // Files of pkg.
+	funcs []string        // Names of the funcs to generate for.
+	err   error           // First error met while generating.
+}
+
+func (g *Generator) Printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// AddFunc adds funcs to generate for.
+func (g *Generator) AddFunc(names ...string) {
+	g.funcs = append(g.funcs, names...)
+}
+
+// Parse parses the package of the funcs: either one directory or a list of
+// files of a single package. Default is the current directory.
+func (g *Generator) Parse(args ...string) error {
+	pkg, err := loader.Load(args...)
+	if err != nil {
+		return err
+	}
+	g.pkg = pkg
+	g.files = nil
+	for _, file := range pkg.Files {
+		g.files = append(g.files, &File{
+			file: file,
+			pkg:  pkg,
+		})
+	}
+	return nil
+}
+
+// Render generates a handler for every func added, and writes it formatted
+// to w.
+func (g *Generator) Render(w io.Writer) error {
+	if g.pkg == nil {
+		return errors.New("no package parsed")
+	}
+	g.buf.Reset()
+	g.err = nil
+
+	// Print the header and package clause.
+	by := g.By
+	if by == "" {
+		by = "varhandlergen"
+	}
+	version := ""
+	if g.Version != "" {
+		version = " (" + g.Version + ")"
+	}
+	g.Printf("// Code generated by \"%s\"%s; DO NOT EDIT\n", by, version)
+	g.Printf("\n")
+	g.Printf("package %s\n", g.pkg.Name)
+	g.Printf("\n")
+	g.Printf("import \"net/http\"\n") // Used by all methods.
+
+	var definitions []FuncDefinition
+
+	for _, funcName := range g.funcs {
+		// generate import for func if any
+		// and generate definition of func for latter call
+		definitions = append(definitions, g.generateImportPaths(funcName))
+	}
+	if g.err != nil {
+		return g.err
+	}
+	for _, definition := range definitions {
+		if definition.Name != "" { // func was found
+			log.Printf("Defining: %s", definition.Name)
+			g.writeFuncDef(definition)
+		}
+	}
+	if g.err != nil {
+		return g.err
+	}
+	// Format the output.
+	_, err := w.Write(g.format())
+	return err
+}
+
+// WriteHelpers copies varhandler_helpers.go, defining the funcs generated
+// handlers call, to dir.
+func (g *Generator) WriteHelpers(dir string) error {
+	utilsFile := "varhandler_helpers.go"
+
+	// copy file utils
+	_, currFile, _, ok := runtime.Caller(0)
+	if !ok {
+		return errors.New("No caller information")
+	}
+
+	utils.CopyFile(filepath.Join(dir, utilsFile), filepath.Join(filepath.Dir(currFile), "..", "varhandler", utilsFile), utils.WriteSkipSame)
+	return nil
+}
+
+// File holds a single parsed file and associated data.
+type File struct {
+	pkg  *loader.Package // Package to which this file belongs.
+	file *ast.File       // Parsed AST.
+
+	// These fields are reset for each func being generated.
+	funcDefinition FuncDefinition
+	found          bool
+}
+
+// generateImportPaths parses the funcs that are going to be called
+// and generates import paths if any generator is in another pkg
+func (g *Generator) generateImportPaths(funcName string) FuncDefinition {
+	found := false
+	for _, file := range g.files {
+		// Set the state for this run of the walker.
+		file.found = false
+		file.funcDefinition = FuncDefinition{
+			Name: funcName,
+		}
+		if file.file != nil {
+			ast.Inspect(file.file, file.genDecl)
+			if file.found {
+				for _, param := range file.funcDefinition.Params {
+					if param.Package != "" {
+						imported := false
+						for _, pkg := range g.pkg.Types.Imports() {
+							if pkg.Name() == param.Package {
+								g.Printf("import %s \"%s\"\n", param.Package, pkg.Path())
+								imported = true
+							}
+						}
+						if imported == false && g.err == nil {
+							g.err = fmt.Errorf("could not find pkg %s", param.Package)
+						}
+					}
+				}
+
+				found = true
+				return file.funcDefinition
+			}
+		}
+	}
+
+	if !found {
+		fmt.Printf("Func not found: %s", funcName)
+	}
+	return FuncDefinition{}
+}
+
+// format returns the gofmt-ed contents of the Generator's buffer.
+func (g *Generator) format() []byte {
+	src, err := format.Source(g.buf.Bytes())
+	if err != nil {
+		// Should never happen, but can arise when developing this code.
+		// The user can compile the output to see the error.
+		log.Printf("warning: internal error: invalid Go generated: %s", err)
+		log.Printf("warning: compile the package to analyze the error")
+		return g.buf.Bytes()
+	}
+	return src
+}
+
+// genDecl processes one declaration clause.
+func (f *File) genDecl(node ast.Node) bool {
+	decl, ok := node.(*ast.FuncDecl)
+	if !ok {
+		// We only care about func declarations.
+		return true
+	}
+	if decl.Name.Name == f.funcDefinition.Name {
+		if len(decl.Type.Params.List) == 0 {
+			log.Printf("%s should take at least one parameter, found %d instead", f.funcDefinition.Name, len(decl.Type.Params.List))
+			return false
+		}
+		ok := f.funcDefinition.ParseResults(decl.Type.Results)
+		if ok {
+			ok = f.funcDefinition.ParseArguments(decl.Type.Params.List)
+		}
+
+		f.found = ok
+	}
+	return false
+}
+
+// writeFuncDef generates an handler func
+func (g *Generator) writeFuncDef(fd FuncDefinition) {
+	funcMap := template.FuncMap{
+		"ToLower": strings.ToLower,
+	}
+
+	t := template.Must(template.New("varhandler").Funcs(funcMap).Parse(handlerWrap))
+
+	err := t.Execute(&g.buf, fd)
+	if err != nil && g.err == nil {
+		g.err = err
+	}
+}
+
+const handlerWrap = `
+func {{.Name}}Handler(w http.ResponseWriter, r *http.Request) {
+	var err error
+{{range $i, $param := .Params}}
+	param{{$i}}, err := {{if ne $param.Package ""}}{{$param.Package}}.{{end}}{{$param.GeneratorName}}(r)
+	if err != nil {
+		HandleHTTPErrorWithDefaultStatus(w, r, http.StatusBadRequest, err)
+		return
+	}
+{{end}}
+{{if .Response}}
+	var resp interface{}
+{{end}}
+{{if .Status}}
+	var status int
+{{end}}
+	{{if .Response}}resp, {{end}}{{if .Status}}status, {{end}}err = {{.Name}}({{range $i, $param := .Params}} {{if gt $i 0}},{{end}} param{{$i}}{{end}})
+	if err != nil {
+		HandleHTTPErrorWithDefaultStatus(w, r, http.StatusInternalServerError, err)
+		return
+	}
+{{if .Status}}
+	if status != 0 {
+		w.WriteHeader(status)
+	}
+{{end}}
+{{if .Response}}
+	if resp != nil {
+		HandleHTTPResponse(w, r, resp)
+	}
+{{end}}
+}
+`
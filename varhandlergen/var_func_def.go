@@ -1,4 +1,4 @@
-package main
+package varhandlergen
 
 import (
 	"fmt"
@@ -8,9 +8,9 @@ import (
 	_ "go/importer"
 )
 
-//FuncDefinition represents
-//the definition of a function
-//that's going to be called by the generated code
+// FuncDefinition represents
+// the definition of a function
+// that's going to be called by the generated code
 type FuncDefinition struct {
 	Name string // of the function
 